@@ -0,0 +1,96 @@
+// Package backstageottl provides the BackstageLookup OTTL function, reading the fields of the
+// catalog entities in OTTL statements, such as the ones of the transform processor:
+//
+//	set(attributes["team"], BackstageLookup(resource.attributes["service.name"], "spec.owner"))
+package backstageottl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// FunctionName is the name of the lookup function in OTTL statements.
+const FunctionName = "BackstageLookup"
+
+// labelPrefix is the prefix of the fields reading an entity label
+const labelPrefix = "metadata.labels."
+
+// fields maps the fields accepted by the lookup function to the entity data
+var fields = map[string]func(catalog.EntityInfo) string{
+	"kind":               func(info catalog.EntityInfo) string { return info.Kind },
+	"metadata.name":      func(info catalog.EntityInfo) string { return info.Name },
+	"metadata.namespace": func(info catalog.EntityInfo) string { return info.Namespace },
+	"spec.owner":         func(info catalog.EntityInfo) string { return info.Owner },
+	"spec.system":        func(info catalog.EntityInfo) string { return info.System },
+	"spec.lifecycle":     func(info catalog.EntityInfo) string { return info.Lifecycle },
+	"entity.ref":         func(info catalog.EntityInfo) string { return info.EntityRef },
+	"org":                func(info catalog.EntityInfo) string { return info.Org },
+	"division":           func(info catalog.EntityInfo) string { return info.Division },
+	"repository":         func(info catalog.EntityInfo) string { return info.Repository },
+	"source":             func(info catalog.EntityInfo) string { return info.Source },
+}
+
+// LookupArguments are the arguments of the lookup function: the lookup key or entity
+// reference, and the entity field to return.
+type LookupArguments[K any] struct {
+	Value ottl.StringGetter[K]
+	Field string
+}
+
+// NewLookupFactory returns the factory of the BackstageLookup function, looking up the
+// entities in the given catalog. The catalog of a backstagecatalog extension is shared with
+// backstagecatalogextension.Provider.
+//
+// The function looks the value up as the service_name match strategy does, and returns the
+// requested field of the entity: kind, metadata.name, metadata.namespace, metadata.labels.<key>,
+// spec.owner, spec.system, spec.lifecycle, entity.ref, org, division, repository or source.
+// It returns nil when no entity matches or the field is empty, which leaves the target of a
+// set statement untouched.
+func NewLookupFactory[K any](provider catalog.Provider) ottl.Factory[K] {
+	return ottl.NewFactory(FunctionName, &LookupArguments[K]{}, func(_ ottl.FunctionContext, oArgs ottl.Arguments) (ottl.ExprFunc[K], error) {
+		args, ok := oArgs.(*LookupArguments[K])
+		if !ok {
+			return nil, errors.New("BackstageLookupFactory args must be of type *LookupArguments[K]")
+		}
+		return lookup(provider, args.Value, args.Field)
+	})
+}
+
+// lookup returns the function reading a field of the entity of the value
+func lookup[K any](provider catalog.Provider, value ottl.StringGetter[K], field string) (ottl.ExprFunc[K], error) {
+	read, err := fieldReader(field)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, tCtx K) (any, error) {
+		key, err := value.Get(ctx, tCtx)
+		if err != nil {
+			return nil, err
+		}
+		info, found := provider.Lookup(key)
+		if !found {
+			return nil, nil
+		}
+		if v := read(info); v != "" {
+			return v, nil
+		}
+		return nil, nil
+	}, nil
+}
+
+// fieldReader returns the function reading a field of the entities
+func fieldReader(field string) (func(catalog.EntityInfo) string, error) {
+	if read, ok := fields[field]; ok {
+		return read, nil
+	}
+	if label, ok := strings.CutPrefix(field, labelPrefix); ok && label != "" {
+		return func(info catalog.EntityInfo) string { return info.Labels[label] }, nil
+	}
+	return nil, fmt.Errorf("unknown %s field %q", FunctionName, field)
+}
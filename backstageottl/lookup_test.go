@@ -0,0 +1,76 @@
+package backstageottl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/ottlfuncs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func newParser(t *testing.T, provider catalog.Provider) ottl.Parser[ottlspan.TransformContext] {
+	functions := ottlfuncs.StandardFuncs[ottlspan.TransformContext]()
+	factory := NewLookupFactory[ottlspan.TransformContext](provider)
+	functions[factory.Name()] = factory
+	parser, err := ottlspan.NewParser(functions, componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+	return parser
+}
+
+func TestLookup(t *testing.T) {
+	snapshot := &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{
+		"component:default/checkout": {
+			Kind: "Component", Name: "checkout", Namespace: "default", EntityRef: "component:default/checkout",
+			Owner: "group:default/payments", System: "shop", Lifecycle: "production",
+			Labels: map[string]string{"tier": "1"},
+		},
+	}}
+	parser := newParser(t, snapshot)
+
+	tests := []struct {
+		name      string
+		statement string
+		expected  any
+	}{
+		{name: "owner", statement: `set(attributes["team"], BackstageLookup(resource.attributes["service.name"], "spec.owner"))`, expected: "group:default/payments"},
+		{name: "label", statement: `set(attributes["team"], BackstageLookup("component:checkout", "metadata.labels.tier"))`, expected: "1"},
+		{name: "missing label", statement: `set(attributes["team"], BackstageLookup("component:checkout", "metadata.labels.cost-center"))`},
+		{name: "unknown entity", statement: `set(attributes["team"], BackstageLookup("billing", "spec.owner"))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := parser.ParseStatement(tt.statement)
+			require.NoError(t, err)
+
+			traces := ptrace.NewTraces()
+			rs := traces.ResourceSpans().AppendEmpty()
+			rs.Resource().Attributes().PutStr("service.name", "component:default/checkout")
+			ss := rs.ScopeSpans().AppendEmpty()
+			span := ss.Spans().AppendEmpty()
+
+			_, _, err = statement.Execute(context.Background(), ottlspan.NewTransformContext(span, ss.Scope(), rs.Resource(), ss, rs))
+			require.NoError(t, err)
+
+			team, found := span.Attributes().Get("team")
+			if tt.expected == nil {
+				assert.False(t, found)
+				return
+			}
+			require.True(t, found)
+			assert.Equal(t, tt.expected, team.Str())
+		})
+	}
+}
+
+func TestLookupUnknownField(t *testing.T) {
+	parser := newParser(t, &catalog.Snapshot{})
+	_, err := parser.ParseStatement(`set(attributes["team"], BackstageLookup("checkout", "spec.team"))`)
+	assert.ErrorContains(t, err, `unknown BackstageLookup field "spec.team"`)
+}
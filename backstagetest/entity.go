@@ -0,0 +1,36 @@
+package backstagetest
+
+import "github.com/tdabasinskas/go-backstage/v2/backstage"
+
+// GithubRepository returns a github-repository resource entity, the kind of entity the
+// processor reads its labels from by default.
+func GithubRepository(namespace string, name string, repository string, labels map[string]string) backstage.Entity {
+	return backstage.Entity{
+		Kind: "Resource",
+		Metadata: backstage.EntityMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: map[string]any{
+			"type": "github-repository",
+			"implementation": map[string]any{
+				"spec": map[string]any{"repository": repository},
+			},
+		},
+	}
+}
+
+// Component returns a service component entity with the given labels and annotations.
+func Component(namespace string, name string, labels map[string]string, annotations map[string]string) backstage.Entity {
+	return backstage.Entity{
+		Kind: "Component",
+		Metadata: backstage.EntityMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: map[string]any{"type": "service"},
+	}
+}
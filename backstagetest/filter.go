@@ -0,0 +1,87 @@
+package backstagetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tdabasinskas/go-backstage/v2/backstage"
+)
+
+// filter is a single filter parameter, matching the entities satisfying all of its conditions
+type filter []condition
+
+// condition is a key=value condition of a filter, or a key condition checking that the
+// field exists
+type condition struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// parseFilter parses a comma-separated list of conditions. Keys and values are compared
+// case-insensitively, as Backstage does.
+func parseFilter(s string) filter {
+	var f filter
+	for _, part := range strings.Split(s, ",") {
+		key, value, hasValue := strings.Cut(part, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		f = append(f, condition{key: key, value: strings.ToLower(strings.TrimSpace(value)), hasValue: hasValue})
+	}
+	return f
+}
+
+// matches reports whether the flattened entity satisfies every condition of the filter
+func (f filter) matches(fields map[string][]string) bool {
+	for _, c := range f {
+		values, found := fields[c.key]
+		if !found || (c.hasValue && !slices.Contains(values, c.value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// flatten indexes the fields of an entity by their lowercase dot-separated path, the way
+// Backstage builds its search table. Array elements are indexed under the array path.
+func flatten(e backstage.Entity) (map[string][]string, error) {
+	if e.Metadata.Namespace == "" {
+		e.Metadata.Namespace = backstage.DefaultNamespaceName
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	fields := map[string][]string{}
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				walk(strings.ToLower(path+"."+k), child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(path, child)
+			}
+		case nil:
+		case string:
+			fields[path] = append(fields[path], strings.ToLower(v))
+		default:
+			fields[path] = append(fields[path], strings.ToLower(fmt.Sprint(v)))
+		}
+	}
+	for k, v := range doc {
+		walk(strings.ToLower(k), v)
+	}
+	return fields, nil
+}
@@ -0,0 +1,228 @@
+// Package backstagetest provides an in-memory Backstage catalog API for tests, so that
+// fetching, refresh and failure handling can be tested end to end without network access.
+package backstagetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tdabasinskas/go-backstage/v2/backstage"
+)
+
+// entitiesPath is the path of the catalog API listing the entities.
+const entitiesPath = "/api/catalog/entities"
+
+// Server is a fake Backstage catalog API serving entities from memory. It implements the
+// filter, order, limit and offset parameters of the entities endpoint, and can require a
+// token, inject failures and delay its responses. It is safe for concurrent use, and the
+// entities can be changed between requests.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	entities []backstage.Entity
+	token    string
+	failures []int
+	latency  time.Duration
+	requests []Request
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Path  string
+	Query url.Values
+}
+
+// NewServer starts a server serving the given entities. It is closed at the end of the test.
+func NewServer(t testing.TB, entities ...backstage.Entity) *Server {
+	t.Helper()
+	s := &Server{entities: entities}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetEntities replaces the entities of the catalog.
+func (s *Server) SetEntities(entities ...backstage.Entity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entities = entities
+}
+
+// AddEntities adds entities to the catalog.
+func (s *Server) AddEntities(entities ...backstage.Entity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entities = append(slices.Clone(s.entities), entities...)
+}
+
+// SetToken requires every request to carry the given token, as sent by the Backstage
+// clients in the Authorization header. An empty token disables the check.
+func (s *Server) SetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// FailNext makes the next n requests fail with the given status code.
+func (s *Server) FailNext(n int, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// SetLatency delays every response by the given duration.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// Requests returns the requests received so far, including the failed ones.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Path: r.URL.Path, Query: r.URL.Query()})
+	entities, token, latency := s.entities, s.token, s.latency
+	status := 0
+	if len(s.failures) > 0 {
+		status, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch {
+	case status != 0:
+		writeError(w, status, "injected failure")
+	case token != "" && !authorized(r, token):
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+	case r.URL.Path != entitiesPath:
+		writeError(w, http.StatusNotFound, "not found")
+	case r.Method != http.MethodGet:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		s.listEntities(w, r.URL.Query(), entities)
+	}
+}
+
+// authorized reports whether the request carries the token, either as a bearer token or
+// with the Token scheme used by the processor
+func authorized(r *http.Request, token string) bool {
+	authorization := r.Header.Get("Authorization")
+	return authorization == "Bearer "+token || authorization == "Token "+token
+}
+
+// listEntities serves the entities matching the filters of the query
+func (s *Server) listEntities(w http.ResponseWriter, query url.Values, entities []backstage.Entity) {
+	filters := make([]filter, 0, len(query["filter"]))
+	for _, f := range query["filter"] {
+		filters = append(filters, parseFilter(f))
+	}
+
+	type indexed struct {
+		entity backstage.Entity
+		fields map[string][]string
+	}
+	var matched []indexed
+	for _, e := range entities {
+		fields, err := flatten(e)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(filters) == 0 || slices.ContainsFunc(filters, func(f filter) bool { return f.matches(fields) }) {
+			matched = append(matched, indexed{entity: e, fields: fields})
+		}
+	}
+
+	for _, order := range slices.Backward(query["order"]) {
+		direction, field, ok := strings.Cut(order, ":")
+		if !ok || (direction != "asc" && direction != "desc") {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid order %q", order))
+			return
+		}
+		field = strings.ToLower(field)
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := first(matched[i].fields[field]), first(matched[j].fields[field])
+			if direction == "desc" {
+				return a > b
+			}
+			return a < b
+		})
+	}
+
+	offset, err := intParam(query, "offset")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := intParam(query, "limit")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	matched = matched[min(offset, len(matched)):]
+	if query.Has("limit") {
+		matched = matched[:min(limit, len(matched))]
+	}
+
+	result := make([]backstage.Entity, 0, len(matched))
+	for _, m := range matched {
+		result = append(result, m.entity)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// intParam returns the non-negative integer query parameter, 0 when missing
+func intParam(query url.Values, name string) (int, error) {
+	if !query.Has(name) {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(query.Get(name))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, query.Get(name))
+	}
+	return n, nil
+}
+
+// writeError writes an error in the format of the Backstage API
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":    map[string]string{"name": http.StatusText(status), "message": message},
+		"response": map[string]int{"statusCode": status},
+	})
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
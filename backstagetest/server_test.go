@@ -0,0 +1,158 @@
+package backstagetest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tdabasinskas/go-backstage/v2/backstage"
+)
+
+// list lists the entities of the server with the go-backstage client used by the processor
+func list(t *testing.T, s *Server, token string, options *backstage.ListEntityOptions) ([]backstage.Entity, error) {
+	t.Helper()
+	client, err := backstage.NewClient(s.URL, "", &http.Client{Transport: &tokenTransport{token: token}})
+	require.NoError(t, err)
+	entities, _, err := client.Catalog.Entities.List(context.Background(), options)
+	return entities, err
+}
+
+type tokenTransport struct {
+	token string
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token != "" {
+		req.Header.Set("Authorization", "Token "+t.token)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func names(entities []backstage.Entity) []string {
+	var result []string
+	for _, e := range entities {
+		result = append(result, e.Metadata.Name)
+	}
+	return result
+}
+
+func TestServerFilters(t *testing.T) {
+	server := NewServer(t,
+		GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop"}),
+		GithubRepository("team-b", "search", "acme/search", map[string]string{"org": "discovery"}),
+		Component("", "cart", nil, map[string]string{"backstage.io/kubernetes-id": "cart"}),
+	)
+
+	tests := []struct {
+		name     string
+		filters  []string
+		expected []string
+	}{
+		{name: "no filter", expected: []string{"checkout", "search", "cart"}},
+		{name: "kind and spec", filters: []string{"kind=resource,spec.type=github-repository"}, expected: []string{"checkout", "search"}},
+		{name: "case-insensitive values", filters: []string{"kind=RESOURCE"}, expected: []string{"checkout", "search"}},
+		{name: "default namespace", filters: []string{"kind=resource,metadata.namespace=default"}, expected: []string{"checkout"}},
+		{name: "label", filters: []string{"metadata.labels.org=discovery"}, expected: []string{"search"}},
+		{name: "existing annotation", filters: []string{"metadata.annotations.backstage.io/kubernetes-id"}, expected: []string{"cart"}},
+		{name: "filters are OR-ed", filters: []string{"kind=component", "metadata.name=search"}, expected: []string{"search", "cart"}},
+		{name: "no match", filters: []string{"kind=system"}, expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entities, err := list(t, server, "", &backstage.ListEntityOptions{Filters: tt.filters})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names(entities))
+		})
+	}
+}
+
+func TestServerOrderAndPagination(t *testing.T) {
+	server := NewServer(t,
+		GithubRepository("", "b", "acme/b", nil),
+		GithubRepository("", "c", "acme/c", nil),
+		GithubRepository("", "a", "acme/a", nil),
+	)
+
+	entities, err := list(t, server, "", &backstage.ListEntityOptions{
+		Order: []backstage.ListEntityOrder{{Direction: backstage.OrderDescending, Field: "metadata.name"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "a"}, names(entities))
+
+	for _, tt := range []struct {
+		query    string
+		expected []string
+	}{
+		{query: "?order=asc:metadata.name&limit=2", expected: []string{"a", "b"}},
+		{query: "?order=asc:metadata.name&limit=2&offset=2", expected: []string{"c"}},
+		{query: "?order=asc:metadata.name&offset=5", expected: []string{}},
+	} {
+		resp, err := http.Get(server.URL + entitiesPath + tt.query)
+		require.NoError(t, err)
+		var page []backstage.Entity
+		require.NoError(t, decode(resp, &page))
+		assert.Equal(t, tt.expected, append([]string{}, names(page)...), tt.query)
+	}
+
+	resp, err := http.Get(server.URL + entitiesPath + "?limit=-1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_ = resp.Body.Close()
+}
+
+func TestServerAuthentication(t *testing.T) {
+	server := NewServer(t, GithubRepository("", "checkout", "acme/checkout", nil))
+	server.SetToken("secret")
+
+	_, err := list(t, server, "", nil)
+	assert.Error(t, err)
+	_, err = list(t, server, "wrong", nil)
+	assert.Error(t, err)
+	entities, err := list(t, server, "secret", nil)
+	require.NoError(t, err)
+	assert.Len(t, entities, 1)
+}
+
+func TestServerFailuresAndLatency(t *testing.T) {
+	server := NewServer(t, GithubRepository("", "checkout", "acme/checkout", nil))
+
+	server.FailNext(2, http.StatusServiceUnavailable)
+	_, err := list(t, server, "", nil)
+	assert.Error(t, err)
+	_, err = list(t, server, "", nil)
+	assert.Error(t, err)
+	_, err = list(t, server, "", nil)
+	assert.NoError(t, err)
+
+	server.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	_, err = list(t, server, "", nil)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	assert.Len(t, server.Requests(), 4)
+	assert.Equal(t, entitiesPath, server.Requests()[0].Path)
+}
+
+func TestServerMutations(t *testing.T) {
+	server := NewServer(t, GithubRepository("", "checkout", "acme/checkout", nil))
+
+	server.AddEntities(GithubRepository("", "search", "acme/search", nil))
+	entities, err := list(t, server, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"checkout", "search"}, names(entities))
+
+	server.SetEntities(GithubRepository("", "cart", "acme/cart", nil))
+	entities, err = list(t, server, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cart"}, names(entities))
+}
+
+func decode(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,146 @@
+package backstageprocessor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// defaults of the cardinality limit
+const (
+	defaultCardinalityWindow   = time.Hour
+	defaultCardinalityOverflow = "other"
+)
+
+// cardinalityKeys are the data point attributes whose distinct values are limited
+var cardinalityKeys = []string{orgKey, divisionKey, refKey, sourceKey}
+
+// CardinalityConfig caps the number of distinct values of the attributes added to metric data
+// points, as every distinct value creates new time series.
+type CardinalityConfig struct {
+	// MaxValues is the maximum number of distinct values of each enriched attribute within the
+	// window. The number of values isn't limited when 0.
+	MaxValues int `mapstructure:"max_values"`
+
+	// Window is the duration a value is remembered after it was last seen. Defaults to 1h.
+	Window time.Duration `mapstructure:"window"`
+
+	// OverflowValue replaces the new values once the limit is reached. Defaults to other.
+	OverflowValue string `mapstructure:"overflow_value"`
+}
+
+// Validate checks if the cardinality configuration is valid.
+func (cfg *CardinalityConfig) Validate() error {
+	if cfg.MaxValues < 0 {
+		return errors.New("cardinality.max_values must not be negative")
+	}
+	if cfg.Window < 0 {
+		return errors.New("cardinality.window must not be negative")
+	}
+	return nil
+}
+
+// window returns the configured window, falling back to 1h.
+func (cfg *CardinalityConfig) window() time.Duration {
+	if cfg.Window == 0 {
+		return defaultCardinalityWindow
+	}
+	return cfg.Window
+}
+
+// overflowValue returns the configured overflow value, falling back to other.
+func (cfg *CardinalityConfig) overflowValue() string {
+	if cfg.OverflowValue == "" {
+		return defaultCardinalityOverflow
+	}
+	return cfg.OverflowValue
+}
+
+// cardinalityLimiter tracks the values of the enriched data point attributes over a rolling window
+type cardinalityLimiter struct {
+	config    CardinalityConfig
+	logger    *zap.Logger
+	telemetry *processorTelemetry
+	now       func() time.Time
+
+	mu sync.Mutex
+	// values holds the time every value of an attribute was last seen
+	values map[string]map[string]time.Time
+	// warned holds the time the limit of an attribute was last reported
+	warned map[string]time.Time
+}
+
+// newCardinalityLimiter returns the limiter of the configuration, nil when the values aren't limited
+func newCardinalityLimiter(cfg CardinalityConfig, logger *zap.Logger, telemetry *processorTelemetry) *cardinalityLimiter {
+	if cfg.MaxValues == 0 {
+		return nil
+	}
+	return &cardinalityLimiter{
+		config:    cfg,
+		logger:    logger,
+		telemetry: telemetry,
+		now:       time.Now,
+		values:    map[string]map[string]time.Time{},
+		warned:    map[string]time.Time{},
+	}
+}
+
+// limit replaces the values of the enriched attributes exceeding the limit by the overflow value.
+// A nil limiter leaves the attributes untouched.
+func (l *cardinalityLimiter) limit(ctx context.Context, attributes pcommon.Map) {
+	if l == nil {
+		return
+	}
+	for _, key := range cardinalityKeys {
+		v, ok := attributes.Get(key)
+		if !ok || l.allow(key, v.Str()) {
+			continue
+		}
+		attributes.PutStr(key, l.config.overflowValue())
+		l.telemetry.cardinalityOverflow.Add(ctx, 1, metric.WithAttributes(attribute.String("attribute", key)))
+	}
+}
+
+// allow records a value of an attribute and reports whether it is within the limit
+func (l *cardinalityLimiter) allow(key string, value string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	values, ok := l.values[key]
+	if !ok {
+		values = map[string]time.Time{}
+		l.values[key] = values
+	}
+	if _, ok := values[value]; ok {
+		values[value] = now
+		return true
+	}
+
+	if len(values) >= l.config.MaxValues {
+		// values are only expired when the limit is reached, to make room for the new one
+		for v, seen := range values {
+			if now.Sub(seen) > l.config.window() {
+				delete(values, v)
+			}
+		}
+	}
+	if len(values) < l.config.MaxValues {
+		values[value] = now
+		return true
+	}
+
+	if last, ok := l.warned[key]; !ok || now.Sub(last) > l.config.window() {
+		l.warned[key] = now
+		l.logger.Warn("Cardinality limit reached, collapsing new values",
+			zap.String("attribute", key), zap.Int("max_values", l.config.MaxValues),
+			zap.String("overflow_value", l.config.overflowValue()))
+	}
+	return false
+}
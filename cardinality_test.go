@@ -0,0 +1,79 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestCardinalityLimit(t *testing.T) {
+	tel := componenttest.NewTelemetry()
+	t.Cleanup(func() { _ = tel.Shutdown(context.Background()) })
+	telemetry, err := newProcessorTelemetry(tel.NewTelemetrySettings())
+	require.NoError(t, err)
+	core, logs := observer.New(zapcore.WarnLevel)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newCardinalityLimiter(CardinalityConfig{MaxValues: 2, Window: time.Minute}, zap.New(core), telemetry)
+	limiter.now = func() time.Time { return now }
+
+	processor := &backstageprocessor{
+		logger: zap.NewNop(),
+		catalog: &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{
+			"checkout": {Org: "shop", Division: "retail"},
+			"billing":  {Org: "payments", Division: "retail"},
+			"search":   {Org: "discovery", Division: "retail"},
+		}},
+		telemetry:   telemetry,
+		cardinality: limiter,
+	}
+	orgOf := func(service string) string {
+		t.Helper()
+		md := pmetric.NewMetrics()
+		dp := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptySum().DataPoints().AppendEmpty()
+		dp.Attributes().PutStr(serviceNameKey, service)
+		_, err := processor.processMetrics(context.Background(), md)
+		require.NoError(t, err)
+		org, _ := dp.Attributes().Get(orgKey)
+		return org.Str()
+	}
+
+	assert.Equal(t, "shop", orgOf("checkout"))
+	assert.Equal(t, "payments", orgOf("billing"))
+	assert.Equal(t, "other", orgOf("search"), "new values collapse once the limit is reached")
+	assert.Equal(t, "shop", orgOf("checkout"), "known values are kept")
+	assert.Equal(t, "other", orgOf("search"))
+	assert.Equal(t, 1, logs.FilterMessage("Cardinality limit reached, collapsing new values").Len(), "the limit is reported once per window")
+
+	got, err := tel.GetMetric("otelcol_backstage_processor_cardinality_overflow")
+	require.NoError(t, err)
+	sum := got.Data.(metricdata.Sum[int64])
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, int64(2), sum.DataPoints[0].Value)
+	assert.Equal(t, attribute.NewSet(attribute.String("attribute", orgKey)), sum.DataPoints[0].Attributes)
+
+	// payments is not seen anymore and expires, checkout is kept alive
+	now = now.Add(45 * time.Second)
+	assert.Equal(t, "shop", orgOf("checkout"))
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, "discovery", orgOf("search"), "expired values make room for new ones")
+	assert.Equal(t, "other", orgOf("billing"))
+}
+
+func TestCardinalityDisabled(t *testing.T) {
+	assert.Nil(t, newCardinalityLimiter(CardinalityConfig{}, zap.NewNop(), nil))
+	assert.EqualError(t, (&CardinalityConfig{MaxValues: -1}).Validate(), "cardinality.max_values must not be negative")
+	assert.EqualError(t, (&CardinalityConfig{Window: -time.Second}).Validate(), "cardinality.window must not be negative")
+}
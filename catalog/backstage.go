@@ -0,0 +1,392 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/tdabasinskas/go-backstage/v2/backstage"
+	"go.uber.org/zap"
+)
+
+type backstageAPITransport struct {
+	apiToken string
+}
+
+func (t *backstageAPITransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Add("Authorization", "Token "+t.apiToken)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type EntityWrapper struct {
+	*backstage.Entity
+}
+
+type GithubRepoSpec struct {
+	Type           string `json:"type"`
+	Lifecycle      string `json:"lifecycle"`
+	Owner          string `json:"owner"`
+	System         string `json:"system"`
+	Implementation struct {
+		Spec struct {
+			Repository string `json:"repository"`
+		} `json:"spec"`
+	} `json:"implementation"`
+}
+
+// EntityInfo is the enrichment data of a catalog entity
+type EntityInfo struct {
+	Repo       string `json:"repo"`
+	Repository string `json:"repository,omitempty"`
+	Org        string `json:"org"`
+	Division   string `json:"division"`
+	Source     string `json:"source"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	EntityRef  string `json:"entityRef"`
+
+	KubernetesID            string `json:"kubernetesId,omitempty"`
+	KubernetesNamespace     string `json:"kubernetesNamespace,omitempty"`
+	KubernetesLabelSelector string `json:"kubernetesLabelSelector,omitempty"`
+
+	Aliases []string `json:"aliases,omitempty"`
+
+	Type      string            `json:"type,omitempty"`
+	Lifecycle string            `json:"lifecycle,omitempty"`
+	Owner     string            `json:"owner,omitempty"`
+	System    string            `json:"system,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+
+	Links []EntityLink `json:"links,omitempty"`
+}
+
+// EntityLink is an entry of the metadata.links of an entity
+type EntityLink struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// annotations read from the catalog entities
+const (
+	KubernetesIDAnnotation            = "backstage.io/kubernetes-id"
+	KubernetesNamespaceAnnotation     = "backstage.io/kubernetes-namespace"
+	KubernetesLabelSelectorAnnotation = "backstage.io/kubernetes-label-selector"
+	ProjectSlugAnnotation             = "github.com/project-slug"
+	SourceLocationAnnotation          = "backstage.io/source-location"
+)
+
+// sourceLabels holds the lookup tables fetched from a single source
+type sourceLabels struct {
+	name          string
+	labels        map[string]EntityInfo
+	kubernetesIDs map[string]EntityInfo
+	selectors     []KubernetesSelector
+}
+
+// KubernetesSelector is the parsed label selector annotation of an entity
+type KubernetesSelector struct {
+	Selector LabelSelector
+	Entity   EntityInfo
+}
+
+// keyCollision records a lookup key claimed by more than one entity of the same source
+type keyCollision struct {
+	Key     string
+	Source  string
+	Kept    string
+	Dropped string
+}
+
+// getRepositoryLabelsMap lists the entities of the source and indexes them. When aliasAnnotation
+// is set, every comma-separated value of that annotation is registered as an extra lookup key.
+func getRepositoryLabelsMap(logger *zap.Logger, source SourceConfig, aliasAnnotation string) (sourceLabels, []keyCollision, error) {
+	entities, err := run(source.Endpoint, string(source.Token), source.Namespace, source.filters())
+	if err != nil {
+		return sourceLabels{}, nil, err
+	}
+	result := sourceLabels{
+		name:          source.Name,
+		labels:        make(map[string]EntityInfo),
+		kubernetesIDs: make(map[string]EntityInfo),
+	}
+	var collisions []keyCollision
+	addKey := func(keys map[string]EntityInfo, key string, info EntityInfo) {
+		if existing, found := keys[key]; found && existing.EntityRef != info.EntityRef {
+			// entities are listed ordered by name, so keeping the first one is deterministic
+			collisions = append(collisions, keyCollision{Key: key, Source: source.Name, Kept: existing.EntityRef, Dropped: info.EntityRef})
+			return
+		}
+		keys[key] = info
+	}
+
+	var aliased []EntityInfo
+	for _, e := range entities {
+		// we need to do a JSON round trip because the `e.Spec` type is `map[string]any`s all the way down. As we know exactly which fields we want, we can do the round trip to a `githubRepoSpec` and then pull the only fields we actually care about here
+		b, err := json.Marshal(e.Spec)
+		if err != nil {
+			return sourceLabels{}, nil, err
+		}
+
+		var spec GithubRepoSpec
+		err = json.Unmarshal(b, &spec)
+		if err != nil {
+			return sourceLabels{}, nil, err
+		}
+
+		namespace := e.Metadata.Namespace
+		if namespace == "" {
+			namespace = backstage.DefaultNamespaceName
+		}
+
+		// repositories in backstage use the org/repo format, either in the github-repository
+		// spec or in the annotations of any other kind of entity
+		repositories := entityRepositories(spec.Implementation.Spec.Repository, e.Metadata.Annotations)
+		var repository string
+		if len(repositories) > 0 {
+			repository = repositories[0]
+		}
+
+		// the service name uses the org - repo format
+		repoInfo := EntityInfo{
+			Repo:       strings.ReplaceAll(repository, "/", "-"),
+			Repository: repository,
+			Division:   e.Metadata.Labels["division"],
+			Org:        e.Metadata.Labels["org"],
+			Source:     source.Name,
+			Kind:       e.Kind,
+			Namespace:  namespace,
+			Name:       e.Metadata.Name,
+			EntityRef:  entityRef(e.Kind, namespace, e.Metadata.Name),
+
+			KubernetesID:            e.Metadata.Annotations[KubernetesIDAnnotation],
+			KubernetesNamespace:     e.Metadata.Annotations[KubernetesNamespaceAnnotation],
+			KubernetesLabelSelector: e.Metadata.Annotations[KubernetesLabelSelectorAnnotation],
+
+			Type:      spec.Type,
+			Lifecycle: spec.Lifecycle,
+			Owner:     spec.Owner,
+			System:    spec.System,
+			Tags:      e.Metadata.Tags,
+			Labels:    e.Metadata.Labels,
+			Links:     entityLinks(e.Metadata.Links),
+		}
+		if aliasAnnotation != "" {
+			repoInfo.Aliases = parseAliases(e.Metadata.Annotations[aliasAnnotation])
+		}
+		if len(repoInfo.Aliases) > 0 {
+			aliased = append(aliased, repoInfo)
+		}
+
+		// the full entity reference is unique within a catalog, the repository name is not
+		result.labels[entityRefKey(repoInfo.EntityRef)] = repoInfo
+		for _, r := range repositories {
+			addKey(result.labels, strings.ReplaceAll(r, "/", "-"), repoInfo)
+			addKey(result.labels, r, repoInfo)
+		}
+
+		if repoInfo.KubernetesID != "" {
+			addKey(result.kubernetesIDs, repoInfo.KubernetesID, repoInfo)
+		}
+		if repoInfo.KubernetesLabelSelector != "" {
+			selector, err := ParseLabelSelector(repoInfo.KubernetesLabelSelector)
+			if err != nil {
+				logger.Warn("Ignoring invalid Kubernetes label selector", zap.String("entity", repoInfo.EntityRef), zap.Error(err))
+				continue
+			}
+			result.selectors = append(result.selectors, KubernetesSelector{Selector: selector, Entity: repoInfo})
+		}
+	}
+
+	// aliases are registered last so that they never shadow the key of another entity
+	for _, info := range aliased {
+		for _, alias := range info.Aliases {
+			addKey(result.labels, alias, info)
+		}
+	}
+
+	return result, collisions, nil
+}
+
+// parseAliases splits a comma-separated list of aliases, skipping the empty ones
+func parseAliases(annotation string) []string {
+	var aliases []string
+	for _, alias := range strings.Split(annotation, ",") {
+		if alias = strings.TrimSpace(alias); alias != "" && !slices.Contains(aliases, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// entityLinks returns the links of an entity, skipping the ones without URL
+func entityLinks(links []backstage.EntityLink) []EntityLink {
+	var result []EntityLink
+	for _, link := range links {
+		if link.URL != "" {
+			result = append(result, EntityLink{URL: link.URL, Title: link.Title, Type: link.Type})
+		}
+	}
+	return result
+}
+
+// entityRepositories returns the distinct repositories of an entity, in the org/repo
+// format, from the github-repository spec and the project slug and source location annotations.
+func entityRepositories(specRepository string, annotations map[string]string) []string {
+	var repositories []string
+	if repository, ok := NormalizeRepository(specRepository); ok {
+		repositories = append(repositories, repository)
+	} else if specRepository != "" {
+		// keep the spec value as is, it has always been used verbatim
+		repositories = append(repositories, specRepository)
+	}
+	for _, candidate := range []string{annotations[ProjectSlugAnnotation], annotations[SourceLocationAnnotation]} {
+		if repository, ok := NormalizeRepository(candidate); ok && !slices.Contains(repositories, repository) {
+			repositories = append(repositories, repository)
+		}
+	}
+	return repositories
+}
+
+// NormalizeRepository reduces a repository slug, URL or source location such as
+// url:https://github.com/org/repo/tree/main/ down to org/repo. Nested groups, as
+// used by GitLab, are kept: url:https://gitlab.com/group/sub/repo/-/tree/main becomes group/sub/repo.
+func NormalizeRepository(location string) (string, bool) {
+	location = strings.TrimSpace(location)
+	location = strings.TrimPrefix(location, "url:")
+	if location == "" {
+		return "", false
+	}
+
+	path := location
+	if u, err := url.Parse(location); err == nil && u.Host != "" {
+		path = u.Path
+	} else if _, rest, ok := strings.Cut(location, "@"); ok {
+		// scp-like git address, git@github.com:org/repo.git
+		_, path, _ = strings.Cut(rest, ":")
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if len(segments) >= 2 && (segment == "-" || segment == "tree" || segment == "blob" || segment == "src") {
+			break
+		}
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) < 2 {
+		return "", false
+	}
+	segments[len(segments)-1] = strings.TrimSuffix(segments[len(segments)-1], ".git")
+	return strings.Join(segments, "/"), true
+}
+
+// entityRef returns the string form of an entity reference, kind:namespace/name
+func entityRef(kind string, namespace string, name string) string {
+	return strings.ToLower(kind) + ":" + strings.ToLower(namespace) + "/" + name
+}
+
+// entityRefKey returns the lookup key of an entity reference. Backstage compares
+// references case-insensitively.
+func entityRefKey(ref string) string {
+	return strings.ToLower(ref)
+}
+
+// parseEntityRef normalizes a kind:namespace/name or kind:name reference into its
+// lookup key. References without a kind are not recognized.
+func parseEntityRef(ref string) (string, bool) {
+	kind, rest, ok := strings.Cut(ref, ":")
+	if !ok || kind == "" || rest == "" {
+		return "", false
+	}
+	namespace, name, ok := strings.Cut(rest, "/")
+	if !ok {
+		namespace, name = backstage.DefaultNamespaceName, rest
+	}
+	if namespace == "" || name == "" {
+		return "", false
+	}
+	return entityRefKey(entityRef(kind, namespace, name)), true
+}
+
+// mergeRepositoryLabels merges the lookup tables of every source into a single snapshot,
+// resolving keys found in more than one source with the given policy.
+func mergeRepositoryLabels(logger *zap.Logger, policy ConflictPolicy, sources []sourceLabels) *Snapshot {
+	merged := &Snapshot{
+		Keys:          mergeKeys(logger, policy, sources, func(s sourceLabels) map[string]EntityInfo { return s.labels }),
+		KubernetesIDs: mergeKeys(logger, policy, sources, func(s sourceLabels) map[string]EntityInfo { return s.kubernetesIDs }),
+	}
+	// selectors are evaluated in order, so the source order gives their precedence
+	for _, src := range sources {
+		merged.Selectors = append(merged.Selectors, src.selectors...)
+	}
+	return merged
+}
+
+// mergeKeys merges one of the lookup tables of every source
+func mergeKeys(logger *zap.Logger, policy ConflictPolicy, sources []sourceLabels, table func(sourceLabels) map[string]EntityInfo) map[string]EntityInfo {
+	merged := make(map[string]EntityInfo)
+	conflicts := make(map[string]bool)
+	for _, src := range sources {
+		for key, info := range table(src) {
+			existing, found := merged[key]
+			if !found && !conflicts[key] {
+				merged[key] = info
+				continue
+			}
+
+			switch policy {
+			case ConflictPolicyLastWins:
+				logger.Debug("Overriding Backstage entry", zap.String("key", key),
+					zap.String("previous source", existing.Source), zap.String("source", src.name))
+				merged[key] = info
+			case ConflictPolicyError:
+				if found {
+					logger.Error("Conflicting Backstage entry, dropping it", zap.String("key", key),
+						zap.String("previous source", existing.Source), zap.String("source", src.name))
+				}
+				conflicts[key] = true
+				delete(merged, key)
+			default:
+				logger.Debug("Ignoring duplicated Backstage entry", zap.String("key", key),
+					zap.String("kept source", existing.Source), zap.String("source", src.name))
+			}
+		}
+	}
+	return merged
+}
+
+// run returns a list of entities based on the given conditions
+func run(backstageUrl string, apiToken string, namespace string, filters []string) ([]EntityWrapper, error) {
+	httpClient := &http.Client{}
+	httpClient.Transport = &backstageAPITransport{apiToken: apiToken}
+	c, err := backstage.NewClient(backstageUrl, namespace, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	entities, _, err := c.Catalog.Entities.List(ctx, &backstage.ListEntityOptions{
+		Filters: filters,
+		Order:   []backstage.ListEntityOrder{{Direction: backstage.OrderAscending, Field: "metadata.name"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var wrappedEntities []EntityWrapper
+	for _, entity := range entities {
+		currentEntity := entity
+		wrappedEntity := EntityWrapper{Entity: &currentEntity}
+		wrappedEntities = append(wrappedEntities, wrappedEntity)
+	}
+
+	return wrappedEntities, nil
+}
@@ -0,0 +1,230 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tdabasinskas/go-backstage/v2/backstage"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+)
+
+func TestGetRepositoryLabelsMap(t *testing.T) {
+	server := backstagetest.NewServer(t,
+		backstagetest.GithubRepository("", "repo-a", "org/repo-a", map[string]string{"org": "org-a", "division": "div-a"}),
+		backstagetest.GithubRepository("team-b", "repo-a", "org/repo-a", map[string]string{"org": "org-b", "division": "div-b"}),
+	)
+
+	result, collisions, err := getRepositoryLabelsMap(zap.NewNop(), SourceConfig{Name: "main", Endpoint: server.URL}, "")
+	require.NoError(t, err)
+	labels := result.labels
+
+	t.Run("indexes entities by entity reference", func(t *testing.T) {
+		assert.Equal(t, "org-a", labels["resource:default/repo-a"].Org)
+		assert.Equal(t, "org-b", labels["resource:team-b/repo-a"].Org)
+		assert.Equal(t, "team-b", labels["resource:team-b/repo-a"].Namespace)
+		assert.Equal(t, "main", labels["resource:team-b/repo-a"].Source)
+	})
+
+	t.Run("keeps the first entity of a colliding repository key", func(t *testing.T) {
+		assert.Equal(t, "resource:default/repo-a", labels["org-repo-a"].EntityRef)
+		assert.Equal(t, []keyCollision{
+			{Key: "org-repo-a", Source: "main", Kept: "resource:default/repo-a", Dropped: "resource:team-b/repo-a"},
+			{Key: "org/repo-a", Source: "main", Kept: "resource:default/repo-a", Dropped: "resource:team-b/repo-a"},
+		}, collisions)
+	})
+}
+
+func TestParseEntityRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+		ok   bool
+	}{
+		{ref: "component:default/my-service", want: "component:default/my-service", ok: true},
+		{ref: "Component:Team-A/My-Service", want: "component:team-a/my-service", ok: true},
+		{ref: "component:my-service", want: "component:default/my-service", ok: true},
+		{ref: "my-service"},
+		{ref: "component:"},
+		{ref: ":default/my-service"},
+		{ref: "component:default/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, ok := parseEntityRef(tt.ref)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMergeRepositoryLabels(t *testing.T) {
+	sources := []sourceLabels{
+		{
+			name: "bu1",
+			labels: map[string]EntityInfo{
+				"org-shared": {Repo: "org-shared", Org: "bu1-org", Division: "bu1-division", Source: "bu1"},
+				"org-only1":  {Repo: "org-only1", Org: "bu1-org", Division: "bu1-division", Source: "bu1"},
+			},
+		},
+		{
+			name: "bu2",
+			labels: map[string]EntityInfo{
+				"org-shared": {Repo: "org-shared", Org: "bu2-org", Division: "bu2-division", Source: "bu2"},
+				"org-only2":  {Repo: "org-only2", Org: "bu2-org", Division: "bu2-division", Source: "bu2"},
+			},
+		},
+	}
+
+	t.Run("first wins by default", func(t *testing.T) {
+		merged := mergeRepositoryLabels(zap.NewNop(), "", sources).Keys
+		assert.Len(t, merged, 3)
+		assert.Equal(t, "bu1", merged["org-shared"].Source)
+	})
+
+	t.Run("last wins", func(t *testing.T) {
+		merged := mergeRepositoryLabels(zap.NewNop(), ConflictPolicyLastWins, sources).Keys
+		assert.Len(t, merged, 3)
+		assert.Equal(t, "bu2", merged["org-shared"].Source)
+	})
+
+	t.Run("error drops the conflicting key", func(t *testing.T) {
+		merged := mergeRepositoryLabels(zap.NewNop(), ConflictPolicyError, append(sources, sourceLabels{
+			name:   "bu3",
+			labels: map[string]EntityInfo{"org-shared": {Repo: "org-shared", Source: "bu3"}},
+		})).Keys
+		assert.Len(t, merged, 2)
+		assert.NotContains(t, merged, "org-shared")
+		assert.Contains(t, merged, "org-only1")
+		assert.Contains(t, merged, "org-only2")
+	})
+}
+
+func TestGetRepositoryLabelsMapKubernetesAnnotations(t *testing.T) {
+	checkout := backstagetest.GithubRepository("", "checkout", "org/checkout", nil)
+	checkout.Metadata.Annotations = map[string]string{
+		KubernetesIDAnnotation:        "checkout",
+		KubernetesNamespaceAnnotation: "shop",
+	}
+	search := backstagetest.GithubRepository("", "search", "org/search", nil)
+	search.Metadata.Annotations = map[string]string{KubernetesLabelSelectorAnnotation: "app=search"}
+	broken := backstagetest.GithubRepository("", "zz-broken", "org/broken", nil)
+	broken.Metadata.Annotations = map[string]string{KubernetesLabelSelectorAnnotation: "app in search"}
+	server := backstagetest.NewServer(t, checkout, search, broken)
+
+	result, _, err := getRepositoryLabelsMap(zap.NewNop(), SourceConfig{Name: "main", Endpoint: server.URL}, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "shop", result.kubernetesIDs["checkout"].KubernetesNamespace)
+	require.Len(t, result.selectors, 1, "invalid selectors are skipped")
+	assert.Equal(t, "resource:default/search", result.selectors[0].Entity.EntityRef)
+	assert.Contains(t, result.labels, "org-broken", "entities with invalid selectors are still indexed")
+}
+
+func TestGetRepositoryLabelsMapRepositoryAnnotations(t *testing.T) {
+	slug := backstagetest.Component("", "checkout", map[string]string{"org": "shop"},
+		map[string]string{ProjectSlugAnnotation: "acme/checkout"})
+	location := backstagetest.Component("", "search", map[string]string{"org": "discovery"},
+		map[string]string{SourceLocationAnnotation: "url:https://github.com/acme/search/tree/main/"})
+	server := backstagetest.NewServer(t, slug, location)
+
+	source := SourceConfig{Name: "main", Endpoint: server.URL, Filters: []string{"kind=component"}}
+	result, collisions, err := getRepositoryLabelsMap(zap.NewNop(), source, "")
+	require.NoError(t, err)
+	assert.Empty(t, collisions)
+
+	for key, org := range map[string]string{
+		"acme/checkout": "shop",
+		"acme-checkout": "shop",
+		"acme/search":   "discovery",
+		"acme-search":   "discovery",
+	} {
+		assert.Equal(t, org, result.labels[key].Org, key)
+	}
+	assert.Equal(t, "acme/search", result.labels["acme-search"].Repository)
+}
+
+func TestNormalizeRepository(t *testing.T) {
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{location: "org/repo", want: "org/repo"},
+		{location: "url:https://github.com/org/repo/tree/main/", want: "org/repo"},
+		{location: "url:https://github.com/org/repo/blob/main/catalog-info.yaml", want: "org/repo"},
+		{location: "https://github.com/org/repo.git", want: "org/repo"},
+		{location: "git@github.com:org/repo.git", want: "org/repo"},
+		{location: "url:https://gitlab.com/group/sub/repo/-/tree/main", want: "group/sub/repo"},
+		{location: "https://github.com/org/src", want: "org/src"},
+		{location: "repo"},
+		{location: "url:https://github.com/org"},
+		{location: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			got, ok := NormalizeRepository(tt.location)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetRepositoryLabelsMapAliases(t *testing.T) {
+	const aliasAnnotation = "observability/service-aliases"
+	checkout := backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop"})
+	checkout.Metadata.Annotations = map[string]string{aliasAnnotation: "cart, legacy-checkout,,cart"}
+	search := backstagetest.GithubRepository("", "search", "acme/search", map[string]string{"org": "discovery"})
+	search.Metadata.Annotations = map[string]string{aliasAnnotation: "legacy-checkout,acme-checkout"}
+	server := backstagetest.NewServer(t, checkout, search)
+
+	t.Run("registers every alias as a lookup key", func(t *testing.T) {
+		result, collisions, err := getRepositoryLabelsMap(zap.NewNop(), SourceConfig{Name: "main", Endpoint: server.URL}, aliasAnnotation)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"cart", "legacy-checkout"}, result.labels["cart"].Aliases)
+		assert.Equal(t, "shop", result.labels["cart"].Org)
+		assert.Equal(t, "shop", result.labels["legacy-checkout"].Org)
+		assert.Equal(t, "shop", result.labels["acme-checkout"].Org, "aliases never shadow a repository key")
+		assert.Equal(t, []keyCollision{
+			{Key: "legacy-checkout", Source: "main", Kept: "resource:default/checkout", Dropped: "resource:default/search"},
+			{Key: "acme-checkout", Source: "main", Kept: "resource:default/checkout", Dropped: "resource:default/search"},
+		}, collisions)
+	})
+
+	t.Run("ignores aliases without the annotation configured", func(t *testing.T) {
+		result, collisions, err := getRepositoryLabelsMap(zap.NewNop(), SourceConfig{Name: "main", Endpoint: server.URL}, "")
+		require.NoError(t, err)
+
+		assert.NotContains(t, result.labels, "cart")
+		assert.Empty(t, collisions)
+	})
+}
+
+func TestGetRepositoryLabelsMapFilterFields(t *testing.T) {
+	checkout := backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop", "tier": "sandbox"})
+	checkout.Spec["lifecycle"] = "deprecated"
+	checkout.Spec["owner"] = "group:default/shop-team"
+	checkout.Spec["system"] = "storefront"
+	checkout.Metadata.Tags = []string{"noisy"}
+	checkout.Metadata.Links = []backstage.EntityLink{
+		{URL: "https://runbooks.example.com/checkout", Title: "Runbook", Type: "runbook"},
+		{Title: "Missing URL"},
+	}
+	server := backstagetest.NewServer(t, checkout)
+
+	result, _, err := getRepositoryLabelsMap(zap.NewNop(), SourceConfig{Name: "main", Endpoint: server.URL}, "")
+	require.NoError(t, err)
+
+	info := result.labels["acme-checkout"]
+	assert.Equal(t, "github-repository", info.Type)
+	assert.Equal(t, "deprecated", info.Lifecycle)
+	assert.Equal(t, "group:default/shop-team", info.Owner)
+	assert.Equal(t, "storefront", info.System)
+	assert.Equal(t, []string{"noisy"}, info.Tags)
+	assert.Equal(t, "sandbox", info.Labels["tier"])
+	assert.Equal(t, []EntityLink{{URL: "https://runbooks.example.com/checkout", Title: "Runbook", Type: "runbook"}}, info.Links)
+}
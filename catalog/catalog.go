@@ -0,0 +1,259 @@
+// Package catalog fetches the Backstage catalog and indexes its entities so that
+// telemetry can be matched against them.
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// Catalog holds the lookup tables of the configured sources, refreshing them in the
+// background when a refresh interval is configured.
+type Catalog struct {
+	logger     *zap.Logger
+	config     Config
+	telemetry  *catalogTelemetry
+	snapshot   *Snapshot
+	mapMu      sync.RWMutex            // Protects snapshot for concurrent access
+	sourceMaps map[string]sourceLabels // Last successful fetch per source, only used by Refresh
+	cancel     context.CancelFunc
+	done       chan struct{}
+	reset      chan struct{} // Restarts the refresh interval after a triggered refresh
+
+	refreshMu   sync.Mutex   // Protects the fields below, serializing the refreshes
+	inflight    *refreshCall // Refresh in progress, joined by concurrent refreshes
+	lastTrigger time.Time
+
+	statusMu     sync.RWMutex // Protects the fields below, read by Status
+	refreshes    []RefreshStatus
+	lastError    string
+	sourceStatus map[string]SourceStatus
+}
+
+var _ Provider = (*Catalog)(nil)
+
+// ErrRefreshRateLimited is returned by TriggerRefresh when the previous trigger is too recent.
+var ErrRefreshRateLimited = errors.New("refresh rate limited")
+
+// refreshCall is a single refresh, shared by every caller asking for a refresh while it runs
+type refreshCall struct {
+	done   chan struct{}
+	status RefreshStatus
+	err    error
+}
+
+// New returns a catalog for the given configuration. Nothing is fetched until Start is called.
+func New(set component.TelemetrySettings, cfg Config) (*Catalog, error) {
+	telemetry, err := newCatalogTelemetry(set)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Catalog{
+		logger:     set.Logger,
+		config:     cfg,
+		telemetry:  telemetry,
+		snapshot:   &Snapshot{},
+		sourceMaps: map[string]sourceLabels{},
+		reset:      make(chan struct{}, 1),
+
+		sourceStatus: map[string]SourceStatus{},
+	}, nil
+}
+
+// Start fetches the catalog and starts the background refresh if an interval is configured.
+// A failing fetch is logged and leaves the catalog empty until the next successful refresh.
+func (c *Catalog) Start(_ context.Context) error {
+	if err := c.Refresh(); err != nil {
+		c.logger.Error("Failed to fetch the Backstage labels", zap.Error(err))
+	} else {
+		c.logger.Info("Fetched GitHub repositories", zap.Int("number of repositories", len(c.Snapshot().Keys)))
+	}
+
+	// Start background refresh if interval is configured
+	if c.config.RefreshInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.cancel = cancel
+		c.done = make(chan struct{})
+		c.logger.Info("Starting background refresh", zap.Duration("interval", c.config.RefreshInterval))
+		go c.refreshLoop(ctx)
+	}
+	return nil
+}
+
+// Shutdown stops the background refresh loop if running
+func (c *Catalog) Shutdown(ctx context.Context) error {
+	if c.cancel != nil {
+		c.logger.Info("Shutting down backstage catalog")
+		c.cancel()
+
+		// Wait for refresh loop to finish or context to timeout
+		select {
+		case <-c.done:
+			c.logger.Info("Refresh loop stopped successfully")
+		case <-ctx.Done():
+			c.logger.Warn("Shutdown context timeout while waiting for refresh loop")
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Lookup returns the entity of the given key, which is either a lookup key
+// or an entity reference.
+func (c *Catalog) Lookup(key string) (EntityInfo, bool) {
+	return c.Snapshot().Lookup(key)
+}
+
+// Snapshot returns the current lookup tables.
+func (c *Catalog) Snapshot() *Snapshot {
+	// Thread-safe read access to snapshot
+	c.mapMu.RLock()
+	defer c.mapMu.RUnlock()
+	return c.snapshot
+}
+
+// refreshLoop periodically refreshes the backstage labels map
+func (c *Catalog) refreshLoop(ctx context.Context) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("Stopping refresh loop")
+			return
+		case <-c.reset:
+			ticker.Reset(c.config.RefreshInterval)
+		case <-ticker.C:
+			c.logger.Debug("Refreshing backstage labels")
+			if err := c.Refresh(); err != nil {
+				c.logger.Error("Failed to refresh backstage labels", zap.Error(err))
+				continue
+			}
+			c.logger.Info("Successfully refreshed backstage labels", zap.Int("count", len(c.Snapshot().Keys)))
+		}
+	}
+}
+
+// Refresh fetches the labels of every source and replaces the lookup tables with
+// their merge. A source that fails keeps contributing its last successful fetch, and
+// the tables are left untouched when every source fails. A refresh asked for while
+// another one runs waits for it and returns its result.
+func (c *Catalog) Refresh() error {
+	c.refreshMu.Lock()
+	call := c.startRefresh()
+	c.refreshMu.Unlock()
+
+	<-call.done
+	return call.err
+}
+
+// TriggerRefresh refreshes the catalog immediately and restarts the refresh interval.
+// It joins the refresh in progress if any, and otherwise returns ErrRefreshRateLimited
+// when the previous trigger happened less than minInterval ago.
+func (c *Catalog) TriggerRefresh(ctx context.Context, minInterval time.Duration) (RefreshStatus, error) {
+	c.refreshMu.Lock()
+	call := c.inflight
+	if call == nil {
+		if time.Since(c.lastTrigger) < minInterval {
+			c.refreshMu.Unlock()
+			return RefreshStatus{}, ErrRefreshRateLimited
+		}
+		c.lastTrigger = time.Now()
+		call = c.startRefresh()
+	}
+	c.refreshMu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return RefreshStatus{}, ctx.Err()
+	}
+
+	select {
+	case c.reset <- struct{}{}:
+	default:
+	}
+	return call.status, call.err
+}
+
+// startRefresh returns the refresh in progress, starting one if needed. It must be
+// called with refreshMu held.
+func (c *Catalog) startRefresh() *refreshCall {
+	if c.inflight != nil {
+		return c.inflight
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	c.inflight = call
+	go func() {
+		start := time.Now()
+		call.err = c.refresh()
+		call.status = RefreshStatus{Time: start, Duration: time.Since(start), Keys: len(c.Snapshot().Keys)}
+		if call.err != nil {
+			call.status.Error = call.err.Error()
+		}
+		c.recordRefresh(call.status)
+
+		c.refreshMu.Lock()
+		c.inflight = nil
+		c.refreshMu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+func (c *Catalog) refresh() error {
+	var errs []error
+	var fetched []sourceLabels
+	for _, src := range c.config.sources() {
+		c.logger.Info("Fetching Backstage labels", zap.String("source", src.Name), zap.String("endpoint", src.Endpoint))
+		labels, collisions, err := getRepositoryLabelsMap(c.logger, src, c.config.AliasAnnotation)
+		c.reportCollisions(collisions)
+		c.recordSource(src.Name, labels, time.Now(), err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("source %q: %w", src.Name, err))
+			labels = c.sourceMaps[src.Name]
+			labels.name = src.Name
+		} else {
+			c.sourceMaps[src.Name] = labels
+		}
+		fetched = append(fetched, labels)
+	}
+
+	if len(errs) > 0 && len(errs) == len(fetched) {
+		return errors.Join(errs...)
+	}
+	for _, err := range errs {
+		c.logger.Warn("Using previous Backstage labels for failing source", zap.Error(err))
+	}
+
+	merged := mergeRepositoryLabels(c.logger, c.config.ConflictPolicy, fetched)
+
+	// Update snapshot with write lock
+	c.mapMu.Lock()
+	c.snapshot = merged
+	c.mapMu.Unlock()
+	return nil
+}
+
+// reportCollisions logs and counts the lookup keys claimed by more than one entity
+func (c *Catalog) reportCollisions(collisions []keyCollision) {
+	for _, collision := range collisions {
+		c.logger.Warn("Backstage lookup key claimed by more than one entity",
+			zap.String("key", collision.Key), zap.String("source", collision.Source),
+			zap.String("kept", collision.Kept), zap.String("dropped", collision.Dropped))
+		c.telemetry.keyCollisions.Add(context.Background(), 1, metric.WithAttributes(attribute.String("source", collision.Source)))
+	}
+}
@@ -0,0 +1,375 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+)
+
+// newTestCatalog creates and starts a catalog with no-op telemetry, failing the test on error
+func newTestCatalog(t *testing.T, cfg *Config) *Catalog {
+	t.Helper()
+	c, err := New(componenttest.NewNopTelemetrySettings(), *cfg)
+	require.NoError(t, err)
+	require.NoError(t, c.Start(context.Background()))
+	return c
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	t.Run("catalog with no refresh interval doesn't start goroutine", func(t *testing.T) {
+		cfg := &Config{
+			Endpoint:        backstagetest.NewServer(t).URL,
+			Token:           "test-token",
+			RefreshInterval: 0, // No refresh
+		}
+
+		c := newTestCatalog(t, cfg)
+		assert.Nil(t, c.cancel, "cancel should be nil when refresh is disabled")
+		assert.Nil(t, c.done, "done channel should be nil when refresh is disabled")
+	})
+
+	t.Run("catalog with refresh interval starts goroutine", func(t *testing.T) {
+		cfg := &Config{
+			Endpoint:        backstagetest.NewServer(t).URL,
+			Token:           "test-token",
+			RefreshInterval: 100 * time.Millisecond,
+		}
+
+		c := newTestCatalog(t, cfg)
+		require.NotNil(t, c.cancel, "cancel should be set when refresh is enabled")
+		require.NotNil(t, c.done, "done channel should be set when refresh is enabled")
+
+		// Cleanup
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := c.Shutdown(ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("shutdown stops refresh loop gracefully", func(t *testing.T) {
+		cfg := &Config{
+			Endpoint:        backstagetest.NewServer(t).URL,
+			Token:           "test-token",
+			RefreshInterval: 100 * time.Millisecond,
+		}
+
+		c := newTestCatalog(t, cfg)
+		require.NotNil(t, c.cancel)
+		require.NotNil(t, c.done)
+
+		// Give the goroutine time to start
+		time.Sleep(50 * time.Millisecond)
+
+		// Shutdown with timeout
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		err := c.Shutdown(ctx)
+		elapsed := time.Since(start)
+
+		assert.NoError(t, err, "shutdown should complete successfully")
+		assert.Less(t, elapsed, 1*time.Second, "shutdown should complete quickly")
+
+		// Verify done channel is closed
+		select {
+		case <-c.done:
+			// Success - channel is closed
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("done channel should be closed after shutdown")
+		}
+	})
+
+	t.Run("shutdown with no background goroutine", func(t *testing.T) {
+		cfg := &Config{
+			Endpoint:        backstagetest.NewServer(t).URL,
+			Token:           "test-token",
+			RefreshInterval: 0, // No refresh
+		}
+
+		c := newTestCatalog(t, cfg)
+
+		ctx := context.Background()
+		err := c.Shutdown(ctx)
+		assert.NoError(t, err, "shutdown should work even without background goroutine")
+	})
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	// Note: This test is tricky because we'd need to simulate a stuck refresh loop
+	// For now, we verify that shutdown respects the context timeout
+	t.Run("shutdown respects context timeout", func(t *testing.T) {
+		cfg := &Config{
+			Endpoint:        backstagetest.NewServer(t).URL,
+			Token:           "test-token",
+			RefreshInterval: 10 * time.Millisecond,
+		}
+
+		c := newTestCatalog(t, cfg)
+		require.NotNil(t, c.cancel)
+		require.NotNil(t, c.done)
+
+		// Create a very short timeout context
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Nanosecond)
+		defer cancel()
+
+		// This might fail due to timeout, which is expected behavior
+		err := c.Shutdown(ctx)
+		// We don't assert the error here because timing can be unpredictable in tests
+		// The important thing is that the function returns and doesn't hang
+		_ = err
+
+		// Cleanup with proper context
+		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cleanupCancel()
+		_ = c.Shutdown(cleanupCtx)
+	})
+}
+
+func TestRefreshIntervalConfiguration(t *testing.T) {
+	tests := []struct {
+		name            string
+		refreshInterval time.Duration
+		expectGoroutine bool
+	}{
+		{
+			name:            "zero duration - no refresh",
+			refreshInterval: 0,
+			expectGoroutine: false,
+		},
+		{
+			name:            "positive duration - enable refresh",
+			refreshInterval: 1 * time.Minute,
+			expectGoroutine: true,
+		},
+		{
+			name:            "very short duration",
+			refreshInterval: 1 * time.Millisecond,
+			expectGoroutine: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Endpoint:        backstagetest.NewServer(t).URL,
+				Token:           "test-token",
+				RefreshInterval: tt.refreshInterval,
+			}
+
+			c := newTestCatalog(t, cfg)
+
+			if tt.expectGoroutine {
+				assert.NotNil(t, c.cancel, "cancel should be set")
+				assert.NotNil(t, c.done, "done should be set")
+
+				// Cleanup
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = c.Shutdown(ctx)
+			} else {
+				assert.Nil(t, c.cancel, "cancel should be nil")
+				assert.Nil(t, c.done, "done should be nil")
+			}
+		})
+	}
+}
+
+func TestCatalogIntegration(t *testing.T) {
+	t.Run("catalog lifecycle", func(t *testing.T) {
+		// Verify that catalog properly integrates with the collector lifecycle
+		cfg := &Config{
+			Endpoint:        backstagetest.NewServer(t).URL,
+			Token:           "test-token",
+			RefreshInterval: 100 * time.Millisecond,
+		}
+
+		c := newTestCatalog(t, cfg)
+		require.NotNil(t, c)
+		require.NotNil(t, c.cancel, "background goroutine should be started")
+
+		// Give it some time to run
+		time.Sleep(150 * time.Millisecond)
+
+		// Shutdown the catalog
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := c.Shutdown(shutdownCtx)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	t.Run("replaces the snapshot", func(t *testing.T) {
+		server := backstagetest.NewServer(t,
+			backstagetest.GithubRepository("", "service1", "org/service1", map[string]string{"org": "org1", "division": "div1"}),
+		)
+		c := newTestCatalog(t, &Config{Endpoint: server.URL})
+
+		info, ok := c.Lookup("org-service1")
+		require.True(t, ok)
+		assert.Equal(t, "org1", info.Org)
+		assert.Equal(t, defaultSourceName, info.Source)
+	})
+
+	t.Run("picks up catalog changes", func(t *testing.T) {
+		server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", map[string]string{"org": "org1"}))
+		server.SetToken("test-token")
+		c := newTestCatalog(t, &Config{Endpoint: server.URL, Token: "test-token"})
+		require.Contains(t, c.Snapshot().Keys, "org-service1")
+
+		server.SetEntities(backstagetest.GithubRepository("", "service1", "org/service1", map[string]string{"org": "org2"}))
+		require.NoError(t, c.Refresh())
+		info, ok := c.Lookup("org-service1")
+		require.True(t, ok)
+		assert.Equal(t, "org2", info.Org)
+	})
+
+	t.Run("fails with an invalid token", func(t *testing.T) {
+		server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+		server.SetToken("test-token")
+		c := newTestCatalog(t, &Config{Endpoint: server.URL, Token: "wrong-token"})
+
+		assert.Error(t, c.Refresh())
+		assert.Empty(t, c.Snapshot().Keys)
+	})
+
+	t.Run("keeps the previous entries of a failing source", func(t *testing.T) {
+		healthy := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+		flaky := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service2", "org/service2", nil))
+		c := newTestCatalog(t, &Config{
+			Sources: []SourceConfig{
+				{Name: "healthy", Endpoint: healthy.URL},
+				{Name: "flaky", Endpoint: flaky.URL},
+			},
+		})
+		require.Contains(t, c.Snapshot().Keys, "org-service2")
+
+		flaky.Close()
+		require.NoError(t, c.Refresh())
+		assert.Contains(t, c.Snapshot().Keys, "org-service1")
+		assert.Contains(t, c.Snapshot().Keys, "org-service2")
+	})
+
+	t.Run("fails when every source fails", func(t *testing.T) {
+		server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+		c := newTestCatalog(t, &Config{Endpoint: server.URL})
+
+		server.Close()
+		assert.Error(t, c.Refresh())
+		assert.Contains(t, c.Snapshot().Keys, "org-service1")
+	})
+
+	t.Run("concurrent lookups during refresh", func(t *testing.T) {
+		server := backstagetest.NewServer(t,
+			backstagetest.GithubRepository("", "service1", "org/service1", map[string]string{"org": "org1"}),
+		)
+		c := newTestCatalog(t, &Config{Endpoint: server.URL, RefreshInterval: 5 * time.Millisecond})
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = c.Shutdown(ctx)
+		}()
+
+		done := make(chan bool)
+		go func() {
+			for i := 0; i < 100; i++ {
+				info, ok := c.Lookup("org-service1")
+				assert.True(t, ok)
+				assert.Equal(t, "org1", info.Org)
+				time.Sleep(1 * time.Millisecond)
+			}
+			done <- true
+		}()
+		<-done
+	})
+}
+
+func TestTriggerRefresh(t *testing.T) {
+	t.Run("coalesces concurrent triggers", func(t *testing.T) {
+		server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+		c := newTestCatalog(t, &Config{Endpoint: server.URL})
+		server.SetLatency(100 * time.Millisecond)
+
+		var wg sync.WaitGroup
+		statuses := make([]RefreshStatus, 3)
+		for i := range statuses {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				status, err := c.TriggerRefresh(context.Background(), 0)
+				assert.NoError(t, err)
+				statuses[i] = status
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, server.Requests(), 2, "the initial fetch and a single triggered one")
+		assert.Equal(t, statuses[0], statuses[1])
+		assert.Equal(t, statuses[0], statuses[2])
+		assert.Equal(t, 3, statuses[0].Keys)
+	})
+
+	t.Run("rate limits triggers", func(t *testing.T) {
+		server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+		c := newTestCatalog(t, &Config{Endpoint: server.URL})
+
+		_, err := c.TriggerRefresh(context.Background(), time.Hour)
+		require.NoError(t, err)
+		_, err = c.TriggerRefresh(context.Background(), time.Hour)
+		assert.ErrorIs(t, err, ErrRefreshRateLimited)
+		_, err = c.TriggerRefresh(context.Background(), 0)
+		assert.NoError(t, err)
+	})
+
+	t.Run("reports a failing refresh", func(t *testing.T) {
+		server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+		c := newTestCatalog(t, &Config{Endpoint: server.URL})
+
+		server.FailNext(1, http.StatusInternalServerError)
+		status, err := c.TriggerRefresh(context.Background(), 0)
+		assert.Error(t, err)
+		assert.Equal(t, err.Error(), status.Error)
+		assert.Equal(t, 3, status.Keys, "keeps the previous lookup tables")
+	})
+
+	t.Run("restarts the refresh interval", func(t *testing.T) {
+		server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+		c := newTestCatalog(t, &Config{Endpoint: server.URL, RefreshInterval: time.Hour})
+		defer func() { _ = c.Shutdown(context.Background()) }()
+
+		_, err := c.TriggerRefresh(context.Background(), 0)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return len(c.reset) == 0 }, 5*time.Second, time.Millisecond,
+			"the refresh loop should consume the reset")
+	})
+}
+
+func TestReportCollisions(t *testing.T) {
+	tel := componenttest.NewTelemetry()
+	defer func() { _ = tel.Shutdown(context.Background()) }()
+
+	c, err := New(tel.NewTelemetrySettings(), Config{})
+	require.NoError(t, err)
+
+	c.reportCollisions([]keyCollision{
+		{Key: "org-a", Source: "main", Kept: "resource:default/a", Dropped: "resource:team/a"},
+		{Key: "org-b", Source: "main", Kept: "resource:default/b", Dropped: "resource:team/b"},
+	})
+
+	got, err := tel.GetMetric("otelcol_backstage_catalog_key_collisions")
+	require.NoError(t, err)
+	sum, ok := got.Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, int64(2), sum.DataPoints[0].Value)
+}
@@ -0,0 +1,134 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+// defaultSourceName is the name given to the source built from the top level endpoint and token.
+const defaultSourceName = "default"
+
+// defaultFilter selects the GitHub repository resources the labels are read from.
+const defaultFilter = "kind=resource,spec.type=github-repository"
+
+// ConflictPolicy defines how a key found in more than one source is resolved.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyFirstWins keeps the entry of the first source, in configuration order.
+	ConflictPolicyFirstWins ConflictPolicy = "first_wins"
+	// ConflictPolicyLastWins keeps the entry of the last source, in configuration order.
+	ConflictPolicyLastWins ConflictPolicy = "last_wins"
+	// ConflictPolicyError logs the conflict and drops the key from the lookup table.
+	ConflictPolicyError ConflictPolicy = "error"
+)
+
+// Config defines how the catalog is fetched and indexed.
+type Config struct {
+	Token           configopaque.String `mapstructure:"token"`
+	Endpoint        string              `mapstructure:"endpoint"`
+	RefreshInterval time.Duration       `mapstructure:"refresh_interval"`
+
+	// Namespace restricts the entities of the top level endpoint to a single catalog namespace.
+	Namespace string `mapstructure:"namespace"`
+
+	// Filters are the catalog filters used to list the entities of the top level endpoint.
+	Filters []string `mapstructure:"filters"`
+
+	// Sources lists additional Backstage instances. They are merged into a single
+	// lookup table after the top level endpoint, if any.
+	Sources []SourceConfig `mapstructure:"sources"`
+
+	// ConflictPolicy resolves keys found in more than one source. Defaults to first_wins.
+	ConflictPolicy ConflictPolicy `mapstructure:"conflict_policy"`
+
+	// AliasAnnotation names the entity annotation holding a comma-separated list of
+	// former service names, each of them registered as a lookup key of the entity.
+	AliasAnnotation string `mapstructure:"alias_annotation"`
+}
+
+// SourceConfig defines a single Backstage catalog instance.
+type SourceConfig struct {
+	// Name identifies the source in logs and in the backstage.source attribute.
+	Name     string              `mapstructure:"name"`
+	Endpoint string              `mapstructure:"endpoint"`
+	Token    configopaque.String `mapstructure:"token"`
+
+	// Filters are the catalog filters used to list the entities. Each entry is
+	// sent as its own filter parameter, so entries are OR-ed together.
+	Filters []string `mapstructure:"filters"`
+
+	// Namespace restricts the listed entities to a single catalog namespace.
+	Namespace string `mapstructure:"namespace"`
+}
+
+// Validate checks if the catalog configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.Endpoint == "" && len(cfg.Sources) == 0 {
+		return errors.New("either endpoint or sources must be configured")
+	}
+
+	switch cfg.ConflictPolicy {
+	case "", ConflictPolicyFirstWins, ConflictPolicyLastWins, ConflictPolicyError:
+	default:
+		return fmt.Errorf("unknown conflict_policy %q", cfg.ConflictPolicy)
+	}
+
+	names := map[string]bool{}
+	for i, src := range cfg.sources() {
+		if src.Name == "" {
+			return fmt.Errorf("sources[%d]: name must not be empty", i)
+		}
+		if src.Endpoint == "" {
+			return fmt.Errorf("source %q: endpoint must not be empty", src.Name)
+		}
+		if names[src.Name] {
+			return fmt.Errorf("source %q: duplicate name", src.Name)
+		}
+		names[src.Name] = true
+	}
+	return nil
+}
+
+// IsEmpty reports whether none of the catalog settings is configured, as required from the
+// components reading the catalog of an extension.
+func (cfg *Config) IsEmpty() bool {
+	return cfg.Token == "" && cfg.Endpoint == "" && cfg.RefreshInterval == 0 && cfg.Namespace == "" &&
+		len(cfg.Filters) == 0 && len(cfg.Sources) == 0 && cfg.ConflictPolicy == "" && cfg.AliasAnnotation == ""
+}
+
+// sources returns every configured source in precedence order, starting with the
+// one built from the top level endpoint and token.
+func (cfg *Config) sources() []SourceConfig {
+	var sources []SourceConfig
+	if cfg.Endpoint != "" {
+		sources = append(sources, SourceConfig{
+			Name:      defaultSourceName,
+			Endpoint:  cfg.Endpoint,
+			Token:     cfg.Token,
+			Namespace: cfg.Namespace,
+			Filters:   cfg.Filters,
+		})
+	}
+	return append(sources, cfg.Sources...)
+}
+
+// filters returns the catalog filters of the source, falling back to the default one.
+func (src SourceConfig) filters() []string {
+	filters := src.Filters
+	if len(filters) == 0 {
+		filters = []string{defaultFilter}
+	}
+	if src.Namespace == "" {
+		return filters
+	}
+
+	namespaced := make([]string, 0, len(filters))
+	for _, f := range filters {
+		namespaced = append(namespaced, f+",metadata.namespace="+src.Namespace)
+	}
+	return namespaced
+}
@@ -0,0 +1,121 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{
+			name:    "no endpoint nor sources",
+			config:  &Config{},
+			wantErr: "either endpoint or sources must be configured",
+		},
+		{
+			name:   "inline endpoint",
+			config: &Config{Endpoint: "https://backstage.example.com"},
+		},
+		{
+			name: "multiple sources",
+			config: &Config{
+				Sources: []SourceConfig{
+					{Name: "bu1", Endpoint: "https://bu1.example.com"},
+					{Name: "bu2", Endpoint: "https://bu2.example.com"},
+				},
+				ConflictPolicy: ConflictPolicyLastWins,
+			},
+		},
+		{
+			name: "source without name",
+			config: &Config{
+				Sources: []SourceConfig{{Endpoint: "https://bu1.example.com"}},
+			},
+			wantErr: "sources[0]: name must not be empty",
+		},
+		{
+			name: "source without endpoint",
+			config: &Config{
+				Sources: []SourceConfig{{Name: "bu1"}},
+			},
+			wantErr: `source "bu1": endpoint must not be empty`,
+		},
+		{
+			name: "source clashing with the inline endpoint",
+			config: &Config{
+				Endpoint: "https://backstage.example.com",
+				Sources:  []SourceConfig{{Name: defaultSourceName, Endpoint: "https://bu1.example.com"}},
+			},
+			wantErr: `source "default": duplicate name`,
+		},
+		{
+			name: "unknown conflict policy",
+			config: &Config{
+				Endpoint:       "https://backstage.example.com",
+				ConflictPolicy: "random",
+			},
+			wantErr: `unknown conflict_policy "random"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got '%v'", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error '%s', got '%v'", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfigSources(t *testing.T) {
+	config := &Config{
+		Endpoint: "https://backstage.example.com",
+		Token:    "test-token",
+		Sources: []SourceConfig{
+			{Name: "bu1", Endpoint: "https://bu1.example.com", Filters: []string{"kind=component"}, Namespace: "bu1"},
+		},
+	}
+
+	sources := config.sources()
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources, got %d", len(sources))
+	}
+	if sources[0].Name != defaultSourceName || sources[0].Token != "test-token" {
+		t.Errorf("Expected the inline endpoint to be the first source, got '%+v'", sources[0])
+	}
+	if got := sources[0].filters(); len(got) != 1 || got[0] != defaultFilter {
+		t.Errorf("Expected the default filter, got '%v'", got)
+	}
+	if got := sources[1].filters(); len(got) != 1 || got[0] != "kind=component,metadata.namespace=bu1" {
+		t.Errorf("Expected the namespaced filter, got '%v'", got)
+	}
+}
+
+func TestConfigIsEmpty(t *testing.T) {
+	if !(&Config{}).IsEmpty() {
+		t.Error("Expected the zero configuration to be empty")
+	}
+	for _, config := range []*Config{
+		{Token: "test-token"},
+		{RefreshInterval: time.Minute},
+		{Namespace: "platform"},
+		{Filters: []string{"kind=component"}},
+		{ConflictPolicy: ConflictPolicyLastWins},
+		{AliasAnnotation: "example.com/aliases"},
+	} {
+		if config.IsEmpty() {
+			t.Errorf("Expected '%+v' not to be empty", config)
+		}
+	}
+}
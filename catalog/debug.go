@@ -0,0 +1,123 @@
+package catalog
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+)
+
+// rules reported by the resolve debug endpoint
+const (
+	ruleEntityRef  = "entity_ref"
+	ruleAlias      = "alias"
+	ruleRepository = "repository"
+)
+
+// resolveResponse is the body of the resolve debug endpoint
+type resolveResponse struct {
+	ServiceName string      `json:"service.name"`
+	Matched     bool        `json:"matched"`
+	Key         string      `json:"key,omitempty"`
+	Rule        string      `json:"rule,omitempty"`
+	Entity      *EntityInfo `json:"entity,omitempty"`
+}
+
+// NewDebugHandler returns the handler of the catalog debug endpoints:
+//
+//   - /keys lists the lookup keys and the entity they resolve to,
+//   - /status reports the refresh history and the state of every source,
+//   - /resolve?service.name=<name> shows the entity and the rule a service name matches.
+//
+// Tokens are never part of the responses.
+func NewDebugHandler(c *Catalog) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, c.Snapshot().Keys)
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, c.Status())
+	})
+	mux.HandleFunc("GET /resolve", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("service.name")
+		if name == "" {
+			http.Error(w, "missing service.name query parameter", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, resolve(c.Snapshot(), name))
+	})
+	return mux
+}
+
+// NewRefreshHandler returns the handler of the refresh endpoint, which refreshes the
+// catalog immediately and reports the result of the refresh. Requests must carry the
+// given token as a bearer token. Concurrent requests share a single refresh, and a
+// new refresh is only started if the previous one was triggered at least minInterval ago.
+func NewRefreshHandler(c *Catalog, token configopaque.String, minInterval time.Duration) http.Handler {
+	expected := []byte("Bearer " + string(token))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		status, err := c.TriggerRefresh(r.Context(), minInterval)
+		switch {
+		case errors.Is(err, ErrRefreshRateLimited):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		case r.Context().Err() != nil:
+			// the client went away before the refresh completed
+		case err != nil:
+			writeJSON(w, http.StatusBadGateway, status)
+		default:
+			writeJSON(w, http.StatusOK, status)
+		}
+	})
+}
+
+// resolve looks up a service name the same way the service_name match strategy does,
+// reporting which kind of key it matched
+func resolve(snapshot *Snapshot, name string) resolveResponse {
+	response := resolveResponse{ServiceName: name}
+	key := name
+	info, ok := snapshot.Keys[key]
+	if !ok {
+		if ref, isRef := parseEntityRef(name); isRef {
+			key = ref
+			info, ok = snapshot.Keys[key]
+		}
+	}
+	if !ok {
+		return response
+	}
+
+	response.Matched = true
+	response.Key = key
+	response.Entity = &info
+	switch {
+	case isEntityRefKey(key, info):
+		response.Rule = ruleEntityRef
+	case slices.Contains(info.Aliases, key):
+		response.Rule = ruleAlias
+	default:
+		response.Rule = ruleRepository
+	}
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,159 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+)
+
+func TestDebugHandler(t *testing.T) {
+	const aliasAnnotation = "observability/service-aliases"
+	checkout := backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop"})
+	checkout.Metadata.Annotations = map[string]string{aliasAnnotation: "cart"}
+	server := backstagetest.NewServer(t, checkout)
+	c := newTestCatalog(t, &Config{Endpoint: server.URL, Token: "secret-token", AliasAnnotation: aliasAnnotation})
+	handler := NewDebugHandler(c)
+
+	get := func(t *testing.T, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	t.Run("lists the lookup keys", func(t *testing.T) {
+		recorder := get(t, "/keys")
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var keys map[string]EntityInfo
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &keys))
+		assert.Equal(t, "shop", keys["acme-checkout"].Org)
+		assert.Contains(t, keys, "cart")
+		assert.Contains(t, keys, "resource:default/checkout")
+	})
+
+	t.Run("reports the status without tokens", func(t *testing.T) {
+		recorder := get(t, "/status")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.NotContains(t, recorder.Body.String(), "secret-token")
+
+		var status Status
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+		require.Len(t, status.Refreshes, 1)
+		assert.Empty(t, status.Refreshes[0].Error)
+		assert.Equal(t, 4, status.Refreshes[0].Keys)
+		require.Len(t, status.Sources, 1)
+		assert.Equal(t, defaultSourceName, status.Sources[0].Name)
+		assert.Equal(t, server.URL, status.Sources[0].Endpoint)
+		assert.Equal(t, 1, status.Sources[0].Entities)
+		assert.Equal(t, 4, status.Sources[0].Keys)
+		assert.False(t, status.Sources[0].LastSuccess.IsZero())
+	})
+
+	t.Run("resolves a service name", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			serviceName string
+			expected    resolveResponse
+		}{
+			{
+				name:        "repository",
+				serviceName: "acme-checkout",
+				expected:    resolveResponse{ServiceName: "acme-checkout", Matched: true, Key: "acme-checkout", Rule: ruleRepository},
+			},
+			{
+				name:        "alias",
+				serviceName: "cart",
+				expected:    resolveResponse{ServiceName: "cart", Matched: true, Key: "cart", Rule: ruleAlias},
+			},
+			{
+				name:        "entity reference",
+				serviceName: "resource:checkout",
+				expected:    resolveResponse{ServiceName: "resource:checkout", Matched: true, Key: "resource:default/checkout", Rule: ruleEntityRef},
+			},
+			{
+				name:        "unknown service",
+				serviceName: "unknown",
+				expected:    resolveResponse{ServiceName: "unknown"},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				recorder := get(t, "/resolve?service.name="+tt.serviceName)
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response resolveResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+				if tt.expected.Matched {
+					require.NotNil(t, response.Entity)
+					assert.Equal(t, "shop", response.Entity.Org)
+					response.Entity = nil
+				}
+				assert.Equal(t, tt.expected, response)
+			})
+		}
+	})
+
+	t.Run("requires a service name to resolve", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(t, "/resolve").Code)
+	})
+}
+
+func TestStatusRecordsFailures(t *testing.T) {
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+	c := newTestCatalog(t, &Config{Endpoint: server.URL})
+
+	server.Close()
+	require.Error(t, c.Refresh())
+
+	status := c.Status()
+	require.Len(t, status.Refreshes, 2)
+	assert.NotEmpty(t, status.Refreshes[1].Error)
+	assert.Equal(t, status.Refreshes[1].Error, status.LastError)
+	require.Len(t, status.Sources, 1)
+	assert.NotEmpty(t, status.Sources[0].LastError)
+	assert.Equal(t, 1, status.Sources[0].Entities, "keeps the counts of the last successful fetch")
+
+	for i := 0; i < maxRefreshHistory; i++ {
+		_ = c.Refresh()
+	}
+	assert.Len(t, c.Status().Refreshes, maxRefreshHistory)
+}
+
+func TestRefreshHandler(t *testing.T) {
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+	c := newTestCatalog(t, &Config{Endpoint: server.URL})
+	handler := NewRefreshHandler(c, "admin-token", time.Hour)
+
+	post := func(t *testing.T, method string, authorization string) *httptest.ResponseRecorder {
+		t.Helper()
+		request := httptest.NewRequest(method, "/refresh", nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, post(t, http.MethodGet, "Bearer admin-token").Code)
+	assert.Equal(t, http.StatusUnauthorized, post(t, http.MethodPost, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, post(t, http.MethodPost, "Bearer wrong-token").Code)
+
+	recorder := post(t, http.MethodPost, "Bearer admin-token")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var status RefreshStatus
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, 3, status.Keys)
+	assert.Empty(t, status.Error)
+
+	assert.Equal(t, http.StatusTooManyRequests, post(t, http.MethodPost, "Bearer admin-token").Code)
+}
@@ -0,0 +1,139 @@
+package catalog
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// selectorOperator is the operator of a label selector requirement
+type selectorOperator string
+
+const (
+	selectorEquals       selectorOperator = "="
+	selectorNotEquals    selectorOperator = "!="
+	selectorIn           selectorOperator = "in"
+	selectorNotIn        selectorOperator = "notin"
+	selectorExists       selectorOperator = "exists"
+	selectorDoesNotExist selectorOperator = "!"
+)
+
+// selectorRequirement is a single condition of a label selector
+type selectorRequirement struct {
+	key      string
+	operator selectorOperator
+	values   []string
+}
+
+// LabelSelector is a parsed Kubernetes label selector, as used by the
+// backstage.io/kubernetes-label-selector annotation. All requirements must match.
+type LabelSelector []selectorRequirement
+
+// ParseLabelSelector parses the Kubernetes label selector syntax, e.g.
+// "app=checkout,tier in (web,api),!canary".
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	var requirements LabelSelector
+	for _, part := range splitSelector(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		requirement, err := parseRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, requirement)
+	}
+	if len(requirements) == 0 {
+		return nil, fmt.Errorf("empty label selector %q", selector)
+	}
+	return requirements, nil
+}
+
+// splitSelector splits the selector on the commas that are not part of a set of values
+func splitSelector(selector string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+func parseRequirement(part string) (selectorRequirement, error) {
+	if key, ok := strings.CutPrefix(part, "!"); ok {
+		return selectorRequirement{key: strings.TrimSpace(key), operator: selectorDoesNotExist}, nil
+	}
+	if key, value, ok := strings.Cut(part, "!="); ok {
+		return newRequirement(key, selectorNotEquals, value)
+	}
+	if key, value, ok := strings.Cut(part, "=="); ok {
+		return newRequirement(key, selectorEquals, value)
+	}
+	if key, value, ok := strings.Cut(part, "="); ok {
+		return newRequirement(key, selectorEquals, value)
+	}
+
+	fields := strings.Fields(part)
+	if len(fields) == 1 {
+		return selectorRequirement{key: fields[0], operator: selectorExists}, nil
+	}
+	if len(fields) >= 2 && (fields[1] == string(selectorIn) || fields[1] == string(selectorNotIn)) {
+		set := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(part[len(fields[0]):]), fields[1]))
+		if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+			return selectorRequirement{}, fmt.Errorf("invalid set of values in label selector requirement %q", part)
+		}
+		var values []string
+		for _, v := range strings.Split(set[1:len(set)-1], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return selectorRequirement{key: fields[0], operator: selectorOperator(fields[1]), values: values}, nil
+	}
+	return selectorRequirement{}, fmt.Errorf("invalid label selector requirement %q", part)
+}
+
+func newRequirement(key string, operator selectorOperator, value string) (selectorRequirement, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return selectorRequirement{}, fmt.Errorf("missing key in label selector requirement %q", key+string(operator)+value)
+	}
+	return selectorRequirement{key: key, operator: operator, values: []string{strings.TrimSpace(value)}}, nil
+}
+
+// Matches evaluates the selector with the given label getter
+func (s LabelSelector) Matches(label func(key string) (string, bool)) bool {
+	for _, r := range s {
+		value, found := label(r.key)
+		switch r.operator {
+		case selectorEquals, selectorIn:
+			if !found || !slices.Contains(r.values, value) {
+				return false
+			}
+		case selectorNotEquals, selectorNotIn:
+			if found && slices.Contains(r.values, value) {
+				return false
+			}
+		case selectorExists:
+			if !found {
+				return false
+			}
+		case selectorDoesNotExist:
+			if found {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	t.Run("valid selector", func(t *testing.T) {
+		selector, err := ParseLabelSelector("app=checkout, tier in (web, api),env!=dev,canary, !debug, zone notin (eu)")
+		require.NoError(t, err)
+		assert.Equal(t, LabelSelector{
+			{key: "app", operator: selectorEquals, values: []string{"checkout"}},
+			{key: "tier", operator: selectorIn, values: []string{"web", "api"}},
+			{key: "env", operator: selectorNotEquals, values: []string{"dev"}},
+			{key: "canary", operator: selectorExists},
+			{key: "debug", operator: selectorDoesNotExist},
+			{key: "zone", operator: selectorNotIn, values: []string{"eu"}},
+		}, selector)
+	})
+
+	for _, invalid := range []string{"", " , ", "=checkout", "tier in web", "app checkout"} {
+		t.Run("invalid selector "+invalid, func(t *testing.T) {
+			_, err := ParseLabelSelector(invalid)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	labels := map[string]string{"app": "checkout", "tier": "web", "canary": "true"}
+	label := func(key string) (string, bool) {
+		v, ok := labels[key]
+		return v, ok
+	}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{selector: "app=checkout", want: true},
+		{selector: "app==checkout,tier in (web,api)", want: true},
+		{selector: "app=payments"},
+		{selector: "tier notin (web)"},
+		{selector: "env notin (dev)", want: true},
+		{selector: "app!=payments", want: true},
+		{selector: "canary", want: true},
+		{selector: "!canary"},
+		{selector: "env"},
+		{selector: "!env", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := ParseLabelSelector(tt.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, selector.Matches(label))
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Provider gives read access to a loaded catalog. It is implemented by Catalog, by the
+// backstagecatalog extension and by Snapshot itself, which makes a fixed catalog.
+type Provider interface {
+	// Lookup returns the entity of the given key, which is either a lookup key
+	// or an entity reference.
+	Lookup(key string) (EntityInfo, bool)
+	// Snapshot returns the current lookup tables. They must not be modified.
+	Snapshot() *Snapshot
+}
+
+// Snapshot holds the lookup tables of a catalog load. A snapshot is never modified
+// once published, a refresh replaces it as a whole.
+type Snapshot struct {
+	// Keys indexes the entities by entity reference, repository and alias.
+	Keys map[string]EntityInfo
+	// KubernetesIDs indexes the entities by backstage.io/kubernetes-id annotation.
+	KubernetesIDs map[string]EntityInfo
+	// Selectors holds the entities with a backstage.io/kubernetes-label-selector
+	// annotation, in precedence order.
+	Selectors []KubernetesSelector
+}
+
+var _ Provider = (*Snapshot)(nil)
+
+// NewSnapshot returns the snapshot of the given lookup keys, as listed by the keys debug
+// endpoint. The Kubernetes indexes are rebuilt from the entities, the label selectors
+// taking precedence in entity reference order.
+func NewSnapshot(keys map[string]EntityInfo) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Keys:          keys,
+		KubernetesIDs: map[string]EntityInfo{},
+	}
+
+	for _, info := range snapshot.Entities() {
+		if info.KubernetesID != "" {
+			if _, found := snapshot.KubernetesIDs[info.KubernetesID]; !found {
+				snapshot.KubernetesIDs[info.KubernetesID] = info
+			}
+		}
+		if info.KubernetesLabelSelector != "" {
+			selector, err := ParseLabelSelector(info.KubernetesLabelSelector)
+			if err != nil {
+				return nil, fmt.Errorf("entity %q: %w", info.EntityRef, err)
+			}
+			snapshot.Selectors = append(snapshot.Selectors, KubernetesSelector{Selector: selector, Entity: info})
+		}
+	}
+	return snapshot, nil
+}
+
+// Lookup returns the entity of the given key, which is either a lookup key
+// or an entity reference.
+func (s *Snapshot) Lookup(key string) (EntityInfo, bool) {
+	if info, ok := s.Keys[key]; ok {
+		return info, true
+	}
+	if ref, ok := parseEntityRef(key); ok {
+		info, ok := s.Keys[ref]
+		return info, ok
+	}
+	return EntityInfo{}, false
+}
+
+// Entities returns every entity of the snapshot once, in entity reference order.
+func (s *Snapshot) Entities() []EntityInfo {
+	var entities []EntityInfo
+	for key, info := range s.Keys {
+		// every entity is indexed by its entity reference
+		if isEntityRefKey(key, info) {
+			entities = append(entities, info)
+		}
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].EntityRef < entities[j].EntityRef })
+	return entities
+}
+
+// Snapshot returns the snapshot itself.
+func (s *Snapshot) Snapshot() *Snapshot {
+	return s
+}
@@ -0,0 +1,39 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	checkout := EntityInfo{Name: "checkout", EntityRef: "component:default/checkout", KubernetesID: "checkout", KubernetesLabelSelector: "app=checkout"}
+	cart := EntityInfo{Name: "cart", EntityRef: "component:default/cart", KubernetesID: "checkout"}
+
+	t.Run("rebuilds the Kubernetes indexes", func(t *testing.T) {
+		snapshot, err := NewSnapshot(map[string]EntityInfo{
+			"component:default/checkout": checkout,
+			"acme-checkout":              checkout,
+			"component:default/cart":     cart,
+		})
+		require.NoError(t, err)
+
+		assert.Len(t, snapshot.Keys, 3)
+		assert.Equal(t, "cart", snapshot.KubernetesIDs["checkout"].Name, "entities are indexed in entity reference order")
+		require.Len(t, snapshot.Selectors, 1)
+		assert.Equal(t, "checkout", snapshot.Selectors[0].Entity.Name)
+
+		info, ok := snapshot.Lookup("component:checkout")
+		require.True(t, ok)
+		assert.Equal(t, "checkout", info.Name)
+
+		assert.Equal(t, []EntityInfo{cart, checkout}, snapshot.Entities(), "entities are listed once, in entity reference order")
+	})
+
+	t.Run("fails on an invalid label selector", func(t *testing.T) {
+		invalid := EntityInfo{EntityRef: "component:default/invalid", KubernetesLabelSelector: "app in (a"}
+		_, err := NewSnapshot(map[string]EntityInfo{"component:default/invalid": invalid})
+		assert.ErrorContains(t, err, `entity "component:default/invalid"`)
+	})
+}
@@ -0,0 +1,102 @@
+package catalog
+
+import (
+	"time"
+)
+
+// maxRefreshHistory is the number of refreshes kept in the catalog status.
+const maxRefreshHistory = 10
+
+// Status describes the loads of a catalog. It never holds the tokens of the sources.
+type Status struct {
+	// Refreshes lists the most recent refreshes, oldest first.
+	Refreshes []RefreshStatus `json:"refreshes"`
+	// LastError is the error of the last failing refresh, if any.
+	LastError string `json:"lastError,omitempty"`
+	// Sources describes every configured source, in precedence order.
+	Sources []SourceStatus `json:"sources"`
+}
+
+// RefreshStatus describes a single refresh of the catalog.
+type RefreshStatus struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Keys     int           `json:"keys"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// SourceStatus describes the last loads of a single source.
+type SourceStatus struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	// Entities and Keys count the entities and lookup keys of the last successful fetch.
+	Entities    int       `json:"entities"`
+	Keys        int       `json:"keys"`
+	LastSuccess time.Time `json:"lastSuccess,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// Status returns the refresh history of the catalog and the state of its sources.
+func (c *Catalog) Status() Status {
+	c.statusMu.RLock()
+	defer c.statusMu.RUnlock()
+
+	status := Status{
+		Refreshes: append([]RefreshStatus(nil), c.refreshes...),
+		LastError: c.lastError,
+	}
+	for _, src := range c.config.sources() {
+		s := c.sourceStatus[src.Name]
+		s.Name = src.Name
+		s.Endpoint = src.Endpoint
+		status.Sources = append(status.Sources, s)
+	}
+	return status
+}
+
+// recordSource updates the status of a source after a fetch
+func (c *Catalog) recordSource(name string, labels sourceLabels, at time.Time, err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	s := c.sourceStatus[name]
+	if err != nil {
+		s.LastError = err.Error()
+	} else {
+		s.LastError = ""
+		s.LastSuccess = at
+		s.Keys = len(labels.labels)
+		s.Entities = countEntities(labels.labels)
+	}
+	c.sourceStatus[name] = s
+}
+
+// recordRefresh appends a refresh to the history, dropping the oldest one if full
+func (c *Catalog) recordRefresh(refresh RefreshStatus) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	if refresh.Error != "" {
+		c.lastError = refresh.Error
+	}
+	c.refreshes = append(c.refreshes, refresh)
+	if len(c.refreshes) > maxRefreshHistory {
+		c.refreshes = c.refreshes[len(c.refreshes)-maxRefreshHistory:]
+	}
+}
+
+// countEntities counts the entities of a lookup table, each of them being indexed by its entity reference
+func countEntities(labels map[string]EntityInfo) int {
+	n := 0
+	for key, info := range labels {
+		if isEntityRefKey(key, info) {
+			n++
+		}
+	}
+	return n
+}
+
+// isEntityRefKey reports whether key is the entity reference of the entity it indexes
+func isEntityRefKey(key string, info EntityInfo) bool {
+	return info.EntityRef != "" && key == entityRefKey(info.EntityRef)
+}
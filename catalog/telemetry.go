@@ -0,0 +1,30 @@
+package catalog
+
+import (
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const scopeName = "github.com/v1v/opentelemetry-backstage-processor/catalog"
+
+// catalogTelemetry holds the internal metrics reported by the catalog
+type catalogTelemetry struct {
+	keyCollisions metric.Int64Counter
+}
+
+func newCatalogTelemetry(set component.TelemetrySettings) (*catalogTelemetry, error) {
+	meter := set.MeterProvider.Meter(scopeName)
+
+	keyCollisions, err := meter.Int64Counter(
+		"otelcol_backstage_catalog_key_collisions",
+		metric.WithDescription("Number of lookup keys claimed by more than one Backstage entity during a load"),
+		metric.WithUnit("{keys}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &catalogTelemetry{
+		keyCollisions: keyCollisions,
+	}, nil
+}
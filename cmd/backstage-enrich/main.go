@@ -0,0 +1,394 @@
+// Command backstage-enrich runs the Backstage processor enrichment offline: it loads the
+// catalog from the Backstage API or from a saved snapshot, enriches OTLP JSON files of
+// traces, logs and metrics, and reports how their services matched the catalog entities.
+//
+// It is meant to validate the matching settings and the catalog mappings in CI, before
+// rolling out a new collector:
+//
+//	backstage-enrich -endpoint https://backstage.example.com -traces traces.json -output-dir out
+//	backstage-enrich -snapshot keys.json -match-strategies service_name,kubernetes -logs logs.json -output-dir out
+//	backstage-enrich -config collector.yaml -traces traces.json -output-dir out
+//
+// With -config, the processor settings are read from the processors section of a collector
+// configuration, or from a file holding the processor settings alone. The flags set on the
+// command line take precedence over the file.
+//
+// Snapshots are the JSON output of the backstagecatalog extension's /keys debug endpoint,
+// or the file written by -save-snapshot.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/envprovider"
+	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+
+	backstageprocessor "github.com/v1v/opentelemetry-backstage-processor"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// tokenEnv is the environment variable the Backstage API token is read from by default.
+const tokenEnv = "BACKSTAGE_API_TOKEN"
+
+// defaultProcessorID is the processor whose settings are read from a collector configuration by default.
+const defaultProcessorID = "backstageprocessor"
+
+// errUnmatched is returned when -fail-on-unmatched is set and a service matches no entity.
+var errUnmatched = errors.New("some services match no Backstage entity")
+
+// options are the command line flags
+type options struct {
+	catalog         catalog.Config
+	processor       backstageprocessor.Config
+	snapshot        string
+	saveSnapshot    string
+	traces          []string
+	logs            []string
+	metrics         []string
+	outputDir       string
+	report          string
+	failOnUnmatched bool
+	verbose         bool
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "backstage-enrich:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+	opts, err := parseFlags(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	logger := zap.NewNop()
+	if opts.verbose {
+		if logger, err = zap.NewDevelopment(); err != nil {
+			return err
+		}
+	}
+
+	provider, err := loadCatalog(logger, opts)
+	if err != nil {
+		return err
+	}
+	if opts.saveSnapshot != "" {
+		if err := writeJSON(opts.saveSnapshot, provider.Snapshot().Keys); err != nil {
+			return fmt.Errorf("saving snapshot: %w", err)
+		}
+	}
+
+	enricher, err := backstageprocessor.NewEnricher(logger, &opts.processor, provider)
+	if err != nil {
+		return err
+	}
+	if err := enrichFiles(ctx, enricher, opts); err != nil {
+		return err
+	}
+
+	report := enricher.Report()
+	if opts.report == "-" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = writeJSON(opts.report, report)
+	}
+	if err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+
+	if opts.failOnUnmatched && report.Unmatched > 0 {
+		return errUnmatched
+	}
+	return nil
+}
+
+func parseFlags(args []string, stderr io.Writer) (options, error) {
+	var opts options
+	var token, strategies, config, processorID string
+
+	fs := flag.NewFlagSet("backstage-enrich", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&config, "config", "", "collector or processor YAML configuration the processor settings are read from")
+	fs.StringVar(&processorID, "processor", defaultProcessorID, "processor of the -config collector configuration")
+	fs.StringVar(&opts.catalog.Endpoint, "endpoint", "", "Backstage endpoint the catalog is fetched from")
+	fs.StringVar(&token, "token", os.Getenv(tokenEnv), "Backstage API token, defaults to $"+tokenEnv)
+	fs.StringVar(&opts.catalog.Namespace, "namespace", "", "catalog namespace the entities are restricted to")
+	fs.Func("filter", "catalog filter used to list the entities, repeatable", appendTo(&opts.catalog.Filters))
+	fs.StringVar(&opts.catalog.AliasAnnotation, "alias-annotation", "", "entity annotation holding the aliases of a service")
+	fs.StringVar(&opts.snapshot, "snapshot", "", "catalog snapshot to load instead of fetching the catalog")
+	fs.StringVar(&opts.saveSnapshot, "save-snapshot", "", "file the loaded catalog snapshot is saved to")
+	fs.StringVar(&strategies, "match-strategies", "", "comma-separated match strategies, defaults to service_name")
+	fs.StringVar(&opts.processor.Kubernetes.PodLabelPrefix, "pod-label-prefix", "", "prefix of the pod label attributes, defaults to k8s.pod.labels.")
+	fs.BoolVar(&opts.processor.SourceAttribute, "source-attribute", false, "add the backstage.source attribute")
+	fs.Func("traces", "OTLP JSON traces file to enrich, repeatable", appendTo(&opts.traces))
+	fs.Func("logs", "OTLP JSON logs file to enrich, repeatable", appendTo(&opts.logs))
+	fs.Func("metrics", "OTLP JSON metrics file to enrich, repeatable", appendTo(&opts.metrics))
+	fs.StringVar(&opts.outputDir, "output-dir", "", "directory the enriched files are written to")
+	fs.StringVar(&opts.report, "report", "-", "file the match report is written to, - for stdout")
+	fs.BoolVar(&opts.failOnUnmatched, "fail-on-unmatched", false, "exit with an error when a service matches no entity")
+	fs.BoolVar(&opts.verbose, "v", false, "log the catalog loading and the matches to stderr")
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
+	}
+	if fs.NArg() > 0 {
+		return options{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	opts.catalog.Token = configopaque.String(token)
+	if strategies != "" {
+		for _, s := range strings.Split(strategies, ",") {
+			opts.processor.MatchStrategies = append(opts.processor.MatchStrategies, backstageprocessor.MatchStrategy(strings.TrimSpace(s)))
+		}
+	}
+	if opts.snapshot != "" && opts.catalog.Endpoint != "" {
+		return options{}, errors.New("-endpoint and -snapshot must not be set together")
+	}
+	if config != "" {
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		var err error
+		if opts, err = mergeConfig(opts, config, processorID, set); err != nil {
+			return options{}, err
+		}
+	}
+	opts.processor.AliasAnnotation = opts.catalog.AliasAnnotation
+
+	switch {
+	case opts.snapshot == "" && opts.catalog.Endpoint == "" && len(opts.catalog.Sources) == 0:
+		return options{}, errors.New("either -endpoint or -snapshot must be set")
+	case len(opts.traces)+len(opts.logs)+len(opts.metrics) > 0 && opts.outputDir == "":
+		return options{}, errors.New("-output-dir must be set to enrich files")
+	}
+	if err := checkOutputNames(opts.traces, opts.logs, opts.metrics); err != nil {
+		return options{}, err
+	}
+	return opts, nil
+}
+
+// checkOutputNames rejects the input files with the same name, their enriched files would
+// overwrite each other in the output directory
+func checkOutputNames(inputs ...[]string) error {
+	names := map[string]string{}
+	for _, paths := range inputs {
+		for _, path := range paths {
+			name := filepath.Base(path)
+			if previous, ok := names[name]; ok {
+				return fmt.Errorf("%s and %s would both be written to %s in the output directory", previous, path, name)
+			}
+			names[name] = path
+		}
+	}
+	return nil
+}
+
+// mergeConfig returns the options with the processor settings of the configuration file, overridden
+// by the flags set on the command line. The catalog of the file is ignored along with -snapshot.
+func mergeConfig(opts options, path string, processorID string, set map[string]bool) (options, error) {
+	cfg, err := loadConfig(path, processorID)
+	if err != nil {
+		return options{}, err
+	}
+
+	catalogCfg := cfg.CatalogConfig()
+	if set["snapshot"] {
+		catalogCfg = catalog.Config{}
+	}
+	if set["endpoint"] {
+		catalogCfg.Endpoint = opts.catalog.Endpoint
+		catalogCfg.Sources = nil
+	}
+	if set["token"] || catalogCfg.Token == "" {
+		catalogCfg.Token = opts.catalog.Token
+	}
+	if set["namespace"] {
+		catalogCfg.Namespace = opts.catalog.Namespace
+	}
+	if set["filter"] {
+		catalogCfg.Filters = opts.catalog.Filters
+	}
+	if set["alias-annotation"] {
+		catalogCfg.AliasAnnotation = opts.catalog.AliasAnnotation
+	}
+	if set["match-strategies"] {
+		cfg.MatchStrategies = opts.processor.MatchStrategies
+	}
+	if set["pod-label-prefix"] {
+		cfg.Kubernetes.PodLabelPrefix = opts.processor.Kubernetes.PodLabelPrefix
+	}
+	if set["source-attribute"] {
+		cfg.SourceAttribute = opts.processor.SourceAttribute
+	}
+
+	opts.catalog = catalogCfg
+	opts.processor = *cfg
+	return opts, nil
+}
+
+// loadConfig reads the settings of a processor from the processors section of a collector
+// configuration, or from a file holding the processor settings alone. The ${env:NAME}
+// references are resolved as the collector does.
+func loadConfig(path string, processorID string) (*backstageprocessor.Config, error) {
+	resolver, err := confmap.NewResolver(confmap.ResolverSettings{
+		URIs:              []string{"file:" + path},
+		ProviderFactories: []confmap.ProviderFactory{fileprovider.NewFactory(), envprovider.NewFactory()},
+		DefaultScheme:     "env",
+	})
+	if err != nil {
+		return nil, err
+	}
+	conf, err := resolver.Resolve(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if conf.IsSet("processors") {
+		key := "processors" + confmap.KeyDelimiter + processorID
+		if !conf.IsSet(key) {
+			return nil, fmt.Errorf("processor %q not found in %s", processorID, path)
+		}
+		if conf, err = conf.Sub(key); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := backstageprocessor.NewFactory().CreateDefaultConfig().(*backstageprocessor.Config)
+	if err := conf.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// appendTo returns a flag function appending every value to the given list
+func appendTo(list *[]string) func(string) error {
+	return func(v string) error {
+		*list = append(*list, v)
+		return nil
+	}
+}
+
+// loadCatalog reads the snapshot file if any, and fetches the catalog otherwise
+func loadCatalog(logger *zap.Logger, opts options) (catalog.Provider, error) {
+	if opts.snapshot != "" {
+		b, err := os.ReadFile(opts.snapshot)
+		if err != nil {
+			return nil, err
+		}
+		var keys map[string]catalog.EntityInfo
+		if err := json.Unmarshal(b, &keys); err != nil {
+			return nil, fmt.Errorf("reading snapshot %s: %w", opts.snapshot, err)
+		}
+		return catalog.NewSnapshot(keys)
+	}
+
+	if err := opts.catalog.Validate(); err != nil {
+		return nil, err
+	}
+	set := component.TelemetrySettings{Logger: logger, MeterProvider: noop.NewMeterProvider()}
+	c, err := catalog.New(set, opts.catalog)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Refresh(); err != nil {
+		return nil, fmt.Errorf("fetching the catalog: %w", err)
+	}
+	return c, nil
+}
+
+// enrichFiles enriches every input file, writing the result under the output directory
+func enrichFiles(ctx context.Context, enricher *backstageprocessor.Enricher, opts options) error {
+	if opts.outputDir != "" {
+		if err := os.MkdirAll(opts.outputDir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range opts.traces {
+		err := enrichFile(path, opts.outputDir, func(b []byte) ([]byte, error) {
+			td, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(b)
+			if err != nil {
+				return nil, err
+			}
+			if err := enricher.Traces(ctx, td); err != nil {
+				return nil, err
+			}
+			return (&ptrace.JSONMarshaler{}).MarshalTraces(td)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, path := range opts.logs {
+		err := enrichFile(path, opts.outputDir, func(b []byte) ([]byte, error) {
+			ld, err := (&plog.JSONUnmarshaler{}).UnmarshalLogs(b)
+			if err != nil {
+				return nil, err
+			}
+			if err := enricher.Logs(ctx, ld); err != nil {
+				return nil, err
+			}
+			return (&plog.JSONMarshaler{}).MarshalLogs(ld)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, path := range opts.metrics {
+		err := enrichFile(path, opts.outputDir, func(b []byte) ([]byte, error) {
+			md, err := (&pmetric.JSONUnmarshaler{}).UnmarshalMetrics(b)
+			if err != nil {
+				return nil, err
+			}
+			if err := enricher.Metrics(ctx, md); err != nil {
+				return nil, err
+			}
+			return (&pmetric.JSONMarshaler{}).MarshalMetrics(md)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enrichFile enriches a single file, written under the output directory with the same name
+func enrichFile(path string, outputDir string, enrich func([]byte) ([]byte, error)) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := enrich(b)
+	if err != nil {
+		return fmt.Errorf("enriching %s: %w", path, err)
+	}
+	return os.WriteFile(filepath.Join(outputDir, filepath.Base(path)), out, 0o644)
+}
+
+func writeJSON(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	backstageprocessor "github.com/v1v/opentelemetry-backstage-processor"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// writeFile writes a file under the test directory, returning its path
+func writeFile(t *testing.T, dir string, name string, b []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, b, 0o644))
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := json.Marshal(map[string]catalog.EntityInfo{
+		"resource:default/checkout": {Org: "shop", Division: "retail", EntityRef: "resource:default/checkout"},
+		"acme-checkout":             {Org: "shop", Division: "retail", EntityRef: "resource:default/checkout"},
+	})
+	require.NoError(t, err)
+	snapshot := writeFile(t, dir, "keys.json", keys)
+
+	td := ptrace.NewTraces()
+	td.ResourceSpans().AppendEmpty().Resource().Attributes().PutStr("service.name", "acme-checkout")
+	b, err := (&ptrace.JSONMarshaler{}).MarshalTraces(td)
+	require.NoError(t, err)
+	traces := writeFile(t, dir, "traces.json", b)
+
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().Resource().Attributes().PutStr("service.name", "legacy")
+	b, err = (&plog.JSONMarshaler{}).MarshalLogs(ld)
+	require.NoError(t, err)
+	logs := writeFile(t, dir, "logs.json", b)
+
+	t.Run("enriches the files and reports the matches", func(t *testing.T) {
+		outputDir := filepath.Join(dir, "out")
+		var stdout bytes.Buffer
+		err := run(context.Background(), []string{
+			"-snapshot", snapshot, "-traces", traces, "-logs", logs, "-output-dir", outputDir,
+		}, &stdout, &bytes.Buffer{})
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(filepath.Join(outputDir, "traces.json"))
+		require.NoError(t, err)
+		enriched, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(b)
+		require.NoError(t, err)
+		org, _ := enriched.ResourceSpans().At(0).Resource().Attributes().Get("backstage.org")
+		assert.Equal(t, "shop", org.Str())
+
+		var report backstageprocessor.MatchReport
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+		assert.Equal(t, 1, report.Matched)
+		assert.Equal(t, 1, report.Unmatched)
+		require.Len(t, report.Services, 2)
+		assert.Equal(t, "legacy", report.Services[0].ServiceName)
+	})
+
+	t.Run("fails on unmatched services when asked to", func(t *testing.T) {
+		err := run(context.Background(), []string{
+			"-snapshot", snapshot, "-logs", logs, "-output-dir", filepath.Join(dir, "out"), "-fail-on-unmatched",
+			"-report", filepath.Join(dir, "report.json"),
+		}, &bytes.Buffer{}, &bytes.Buffer{})
+		assert.ErrorIs(t, err, errUnmatched)
+		assert.FileExists(t, filepath.Join(dir, "report.json"))
+	})
+
+	t.Run("rejects invalid flags", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			args        []string
+			expectedErr string
+		}{
+			{name: "no catalog", args: nil, expectedErr: "either -endpoint or -snapshot must be set"},
+			{name: "both catalogs", args: []string{"-snapshot", snapshot, "-endpoint", "http://localhost"}, expectedErr: "-endpoint and -snapshot must not be set together"},
+			{name: "no output dir", args: []string{"-snapshot", snapshot, "-traces", traces}, expectedErr: "-output-dir must be set to enrich files"},
+			{name: "unknown strategy", args: []string{"-snapshot", snapshot, "-match-strategies", "service_name,unknown"}, expectedErr: `unknown match strategy "unknown"`},
+			{
+				name:        "same file names",
+				args:        []string{"-snapshot", snapshot, "-output-dir", "out", "-traces", "a/traces.json", "-traces", "b/traces.json"},
+				expectedErr: "a/traces.json and b/traces.json would both be written to traces.json in the output directory",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := run(context.Background(), tt.args, &bytes.Buffer{}, &bytes.Buffer{})
+				assert.EqualError(t, err, tt.expectedErr)
+			})
+		}
+	})
+}
+
+func TestParseFlagsConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TEST_BACKSTAGE_TOKEN", "secret")
+	collector := writeFile(t, dir, "collector.yaml", []byte(`
+processors:
+  batch:
+  backstageprocessor/prod:
+    endpoint: https://backstage.example.com
+    token: ${env:TEST_BACKSTAGE_TOKEN}
+    filters: [kind=component]
+    match_strategies: [service_name, kubernetes]
+    fuzzy_match:
+      case_insensitive: true
+    tenant:
+      field: org
+`))
+
+	t.Run("reads the processor of a collector configuration", func(t *testing.T) {
+		opts, err := parseFlags([]string{"-config", collector, "-processor", "backstageprocessor/prod"}, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, "https://backstage.example.com", opts.catalog.Endpoint)
+		assert.Equal(t, "secret", string(opts.catalog.Token))
+		assert.Equal(t, []string{"kind=component"}, opts.catalog.Filters)
+		assert.Equal(t, []backstageprocessor.MatchStrategy{"service_name", "kubernetes"}, opts.processor.MatchStrategies)
+		assert.True(t, opts.processor.FuzzyMatch.CaseInsensitive)
+		assert.Equal(t, "org", opts.processor.Tenant.Field)
+	})
+
+	t.Run("flags take precedence", func(t *testing.T) {
+		opts, err := parseFlags([]string{
+			"-config", collector, "-processor", "backstageprocessor/prod",
+			"-endpoint", "http://localhost", "-match-strategies", "kubernetes",
+		}, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost", opts.catalog.Endpoint)
+		assert.Equal(t, []string{"kind=component"}, opts.catalog.Filters)
+		assert.Equal(t, []backstageprocessor.MatchStrategy{"kubernetes"}, opts.processor.MatchStrategies)
+		assert.True(t, opts.processor.FuzzyMatch.CaseInsensitive)
+	})
+
+	t.Run("snapshot replaces the catalog of the file", func(t *testing.T) {
+		opts, err := parseFlags([]string{"-config", collector, "-processor", "backstageprocessor/prod", "-snapshot", "keys.json"}, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Empty(t, opts.catalog.Endpoint)
+		assert.Equal(t, "keys.json", opts.snapshot)
+	})
+
+	t.Run("reads the processor settings alone", func(t *testing.T) {
+		settings := writeFile(t, dir, "processor.yaml", []byte("extension: backstagecatalog\nlinks:\n  frontend_url: https://backstage.example.com\n"))
+		opts, err := parseFlags([]string{"-config", settings, "-snapshot", "keys.json"}, &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, "https://backstage.example.com", opts.processor.Links.FrontendURL)
+	})
+
+	t.Run("rejects invalid configurations", func(t *testing.T) {
+		_, err := parseFlags([]string{"-config", collector}, &bytes.Buffer{})
+		assert.EqualError(t, err, `processor "backstageprocessor" not found in `+collector)
+
+		unknown := writeFile(t, dir, "unknown.yaml", []byte("match_strategy: kubernetes\n"))
+		_, err = parseFlags([]string{"-config", unknown, "-snapshot", "keys.json"}, &bytes.Buffer{})
+		assert.ErrorContains(t, err, "match_strategy")
+	})
+}
@@ -0,0 +1,133 @@
+package backstageprocessor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlresource"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/ottlfuncs"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// ConditionsConfig lists the OTTL conditions deciding which telemetry is enriched. Telemetry is
+// enriched when any of the conditions of its context is true, or when its context has none.
+type ConditionsConfig struct {
+	// Resource conditions are evaluated against every resource, in the resource context. The
+	// resources failing them are left untouched along with their spans, logs, metrics and profiles.
+	Resource []string `mapstructure:"resource"`
+
+	// Span conditions are evaluated against every span, in the span context.
+	Span []string `mapstructure:"span"`
+
+	// Log conditions are evaluated against every log record, in the log context.
+	Log []string `mapstructure:"log"`
+}
+
+// enrichConditions holds the parsed conditions, a nil sequence when its context has no condition
+type enrichConditions struct {
+	resource *ottl.ConditionSequence[ottlresource.TransformContext]
+	span     *ottl.ConditionSequence[ottlspan.TransformContext]
+	log      *ottl.ConditionSequence[ottllog.TransformContext]
+}
+
+// validateErrorMode checks that the error mode is a known one, empty meaning propagate.
+func validateErrorMode(mode ottl.ErrorMode) error {
+	switch mode {
+	case "", ottl.PropagateError, ottl.IgnoreError, ottl.SilentError:
+		return nil
+	}
+	return fmt.Errorf("unknown error_mode %q", mode)
+}
+
+// newEnrichConditions parses the conditions of the configuration. It returns nil when no
+// condition is configured.
+func newEnrichConditions(cfg ConditionsConfig, errorMode ottl.ErrorMode, set component.TelemetrySettings) (*enrichConditions, error) {
+	if len(cfg.Resource) == 0 && len(cfg.Span) == 0 && len(cfg.Log) == 0 {
+		return nil, nil
+	}
+	if errorMode == "" {
+		errorMode = ottl.PropagateError
+	}
+
+	c := &enrichConditions{}
+	if len(cfg.Resource) > 0 {
+		parser, err := ottlresource.NewParser(ottlfuncs.StandardConverters[ottlresource.TransformContext](), set)
+		if err != nil {
+			return nil, err
+		}
+		conditions, err := parser.ParseConditions(cfg.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conditions.resource: %w", err)
+		}
+		seq := ottlresource.NewConditionSequence(conditions, set, ottlresource.WithConditionSequenceErrorMode(errorMode))
+		c.resource = &seq
+	}
+	if len(cfg.Span) > 0 {
+		parser, err := ottlspan.NewParser(ottlfuncs.StandardConverters[ottlspan.TransformContext](), set)
+		if err != nil {
+			return nil, err
+		}
+		conditions, err := parser.ParseConditions(cfg.Span)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conditions.span: %w", err)
+		}
+		seq := ottlspan.NewConditionSequence(conditions, set, ottlspan.WithConditionSequenceErrorMode(errorMode))
+		c.span = &seq
+	}
+	if len(cfg.Log) > 0 {
+		parser, err := ottllog.NewParser(ottlfuncs.StandardConverters[ottllog.TransformContext](), set)
+		if err != nil {
+			return nil, err
+		}
+		conditions, err := parser.ParseConditions(cfg.Log)
+		if err != nil {
+			return nil, fmt.Errorf("invalid conditions.log: %w", err)
+		}
+		seq := ottllog.NewConditionSequence(conditions, set, ottllog.WithConditionSequenceErrorMode(errorMode))
+		c.log = &seq
+	}
+	return c, nil
+}
+
+// Validate checks if the conditions are valid OTTL conditions.
+func (cfg *ConditionsConfig) Validate() error {
+	_, err := newEnrichConditions(*cfg, ottl.PropagateError, component.TelemetrySettings{Logger: zap.NewNop()})
+	return err
+}
+
+// schemaURLItem is the resource container of any signal, holding the schema URL of the resource
+type schemaURLItem interface {
+	SchemaUrl() string
+	SetSchemaUrl(v string)
+}
+
+// enrichResource reports whether a resource is enriched. A nil receiver enriches everything.
+func (c *enrichConditions) enrichResource(ctx context.Context, resource pcommon.Resource, item schemaURLItem) (bool, error) {
+	if c == nil || c.resource == nil {
+		return true, nil
+	}
+	return c.resource.Eval(ctx, ottlresource.NewTransformContext(resource, item))
+}
+
+// enrichSpan reports whether a span is enriched. A nil receiver enriches everything.
+func (c *enrichConditions) enrichSpan(ctx context.Context, span ptrace.Span, ss ptrace.ScopeSpans, rs ptrace.ResourceSpans) (bool, error) {
+	if c == nil || c.span == nil {
+		return true, nil
+	}
+	return c.span.Eval(ctx, ottlspan.NewTransformContext(span, ss.Scope(), rs.Resource(), ss, rs))
+}
+
+// enrichLog reports whether a log record is enriched. A nil receiver enriches everything.
+func (c *enrichConditions) enrichLog(ctx context.Context, log plog.LogRecord, sl plog.ScopeLogs, rl plog.ResourceLogs) (bool, error) {
+	if c == nil || c.log == nil {
+		return true, nil
+	}
+	return c.log.Eval(ctx, ottllog.NewTransformContext(log, sl.Scope(), rl.Resource(), sl, rl))
+}
@@ -0,0 +1,171 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func newConditionsProcessor(t *testing.T, conditions ConditionsConfig, errorMode ottl.ErrorMode) *backstageprocessor {
+	parsed, err := newEnrichConditions(conditions, errorMode, componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+	return &backstageprocessor{
+		logger:     zap.NewNop(),
+		config:     Config{Conditions: conditions, ErrorMode: errorMode},
+		catalog:    &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{"checkout": {Org: "shop", Division: "retail"}}},
+		conditions: parsed,
+	}
+}
+
+func TestEnrichConditionsConfig(t *testing.T) {
+	conditions, err := newEnrichConditions(ConditionsConfig{}, "", componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+	assert.Nil(t, conditions)
+
+	assert.ErrorContains(t, (&ConditionsConfig{Resource: []string{`attributes["env"] ==`}}).Validate(), "invalid conditions.resource")
+	assert.ErrorContains(t, (&ConditionsConfig{Span: []string{`kind == SPAN_KIND_UNKNOWN`}}).Validate(), "invalid conditions.span")
+	assert.ErrorContains(t, (&ConditionsConfig{Log: []string{`Unknown(body)`}}).Validate(), "invalid conditions.log")
+	assert.NoError(t, (&ConditionsConfig{
+		Resource: []string{`attributes["deployment.environment"] == "production"`},
+		Span:     []string{`kind == SPAN_KIND_SERVER`},
+		Log:      []string{`severity_number >= SEVERITY_NUMBER_WARN`},
+	}).Validate())
+}
+
+func TestProcessTracesConditions(t *testing.T) {
+	processor := newConditionsProcessor(t, ConditionsConfig{
+		Resource: []string{`attributes["deployment.environment"] == "production"`},
+		Span:     []string{`kind == SPAN_KIND_SERVER`},
+	}, "")
+
+	traces := ptrace.NewTraces()
+	production := traces.ResourceSpans().AppendEmpty()
+	production.Resource().Attributes().PutStr(serviceNameKey, "checkout")
+	production.Resource().Attributes().PutStr("deployment.environment", "production")
+	spans := production.ScopeSpans().AppendEmpty().Spans()
+	server := spans.AppendEmpty()
+	server.SetKind(ptrace.SpanKindServer)
+	server.Attributes().PutStr(serviceNameKey, "checkout")
+	internal := spans.AppendEmpty()
+	internal.SetKind(ptrace.SpanKindInternal)
+	internal.Attributes().PutStr(serviceNameKey, "checkout")
+	staging := traces.ResourceSpans().AppendEmpty()
+	staging.Resource().Attributes().PutStr(serviceNameKey, "checkout")
+	staging.Resource().Attributes().PutStr("deployment.environment", "staging")
+	stagingSpan := staging.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	stagingSpan.SetKind(ptrace.SpanKindServer)
+	stagingSpan.Attributes().PutStr(serviceNameKey, "checkout")
+
+	_, err := processor.processTraces(context.Background(), traces)
+	require.NoError(t, err)
+
+	org, found := production.Resource().Attributes().Get(orgKey)
+	require.True(t, found)
+	assert.Equal(t, "shop", org.Str())
+	_, found = server.Attributes().Get(orgKey)
+	assert.True(t, found, "server span is enriched")
+	_, found = internal.Attributes().Get(orgKey)
+	assert.False(t, found, "internal span is left untouched")
+	_, found = staging.Resource().Attributes().Get(orgKey)
+	assert.False(t, found, "staging resource is left untouched")
+	_, found = stagingSpan.Attributes().Get(orgKey)
+	assert.False(t, found, "spans of the staging resource are left untouched")
+}
+
+func TestProcessLogsConditions(t *testing.T) {
+	processor := newConditionsProcessor(t, ConditionsConfig{Log: []string{`severity_number >= SEVERITY_NUMBER_WARN`}}, "")
+
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr(serviceNameKey, "checkout")
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+	warn := records.AppendEmpty()
+	warn.SetSeverityNumber(plog.SeverityNumberWarn)
+	warn.Attributes().PutStr(serviceNameKey, "checkout")
+	debug := records.AppendEmpty()
+	debug.SetSeverityNumber(plog.SeverityNumberDebug)
+	debug.Attributes().PutStr(serviceNameKey, "checkout")
+
+	_, err := processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	_, found := rl.Resource().Attributes().Get(orgKey)
+	assert.True(t, found, "resource without conditions is enriched")
+	_, found = warn.Attributes().Get(orgKey)
+	assert.True(t, found, "warning is enriched")
+	_, found = debug.Attributes().Get(orgKey)
+	assert.False(t, found, "debug log is left untouched")
+}
+
+func TestProcessConditionsErrorMode(t *testing.T) {
+	// Len fails on the integer attribute of the resource
+	conditions := ConditionsConfig{Resource: []string{`Len(attributes["replicas"]) > 0`}}
+	newMetrics := func() pmetric.Metrics {
+		md := pmetric.NewMetrics()
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr(serviceNameKey, "checkout")
+		rm.Resource().Attributes().PutInt("replicas", 3)
+		return md
+	}
+
+	t.Run("propagate", func(t *testing.T) {
+		md := newMetrics()
+		_, err := newConditionsProcessor(t, conditions, ottl.PropagateError).processMetrics(context.Background(), md)
+		assert.ErrorContains(t, err, "failed to eval condition")
+		_, found := md.ResourceMetrics().At(0).Resource().Attributes().Get(orgKey)
+		assert.False(t, found)
+	})
+
+	for _, mode := range []ottl.ErrorMode{ottl.IgnoreError, ottl.SilentError} {
+		t.Run(string(mode), func(t *testing.T) {
+			md := newMetrics()
+			_, err := newConditionsProcessor(t, conditions, mode).processMetrics(context.Background(), md)
+			require.NoError(t, err)
+			_, found := md.ResourceMetrics().At(0).Resource().Attributes().Get(orgKey)
+			assert.False(t, found, "resource failing its conditions is left untouched")
+		})
+	}
+}
+
+func TestProcessConditionsFilterAndTenant(t *testing.T) {
+	processor := newConditionsProcessor(t, ConditionsConfig{Resource: []string{`attributes["deployment.environment"] == "production"`}}, "")
+	processor.config.Filter = FilterConfig{Unmatched: true}
+	processor.config.Tenant = TenantConfig{Field: "org"}
+	telemetry, err := newProcessorTelemetry(componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+	processor.telemetry = telemetry
+
+	logs := plog.NewLogs()
+	for _, service := range []string{"checkout", "billing"} {
+		rl := logs.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().PutStr(serviceNameKey, service)
+		rl.Resource().Attributes().PutStr("deployment.environment", "staging")
+		records := rl.ScopeLogs().AppendEmpty().LogRecords()
+		records.AppendEmpty().Attributes().PutStr(serviceNameKey, "checkout")
+		records.AppendEmpty().Attributes().PutStr(serviceNameKey, "billing")
+	}
+
+	_, err = processor.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, logs.ResourceLogs().Len(), "unmatched resource is dropped despite its conditions")
+	rl := logs.ResourceLogs().At(0)
+	assert.Equal(t, map[string]any{
+		serviceNameKey:           "checkout",
+		"deployment.environment": "staging",
+		defaultTenantAttribute:   "shop",
+	}, rl.Resource().Attributes().AsRaw(), "the tenant is set but the resource isn't enriched")
+	records := rl.ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 1, records.Len(), "unmatched log is dropped despite the resource conditions")
+	assert.Equal(t, map[string]any{serviceNameKey: "checkout"}, records.At(0).Attributes().AsRaw())
+}
@@ -1,17 +1,203 @@
 package backstageprocessor
 
 import (
+	"errors"
 	"time"
 
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/configopaque"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// MatchStrategy defines how telemetry is matched against the catalog entities.
+type MatchStrategy string
+
+const (
+	// MatchServiceName looks up the service.name attribute by repository name or entity reference.
+	MatchServiceName MatchStrategy = "service_name"
+	// MatchKubernetes matches the k8s.* resource attributes against the
+	// backstage.io/kubernetes-id and backstage.io/kubernetes-label-selector annotations.
+	MatchKubernetes MatchStrategy = "kubernetes"
+	// MatchVCSRepository matches the vcs.repository.* attributes against the repository of
+	// the entities, read from the github-repository spec or the github.com/project-slug and
+	// backstage.io/source-location annotations.
+	MatchVCSRepository MatchStrategy = "vcs_repository"
 )
 
+// defaultPodLabelPrefix is the prefix used by the k8sattributes processor for pod labels.
+const defaultPodLabelPrefix = "k8s.pod.labels."
+
 // Config defines configuration for Resource processor.
 type Config struct {
 	Token           configopaque.String `mapstructure:"token"`
 	Endpoint        string              `mapstructure:"endpoint"`
 	RefreshInterval time.Duration       `mapstructure:"refresh_interval"`
+
+	// Namespace restricts the entities of the top level endpoint to a single catalog namespace.
+	Namespace string `mapstructure:"namespace"`
+
+	// Filters are the catalog filters used to list the entities of the top level endpoint.
+	Filters []string `mapstructure:"filters"`
+
+	// Sources lists additional Backstage instances. They are merged into a single
+	// lookup table after the top level endpoint, if any.
+	Sources []catalog.SourceConfig `mapstructure:"sources"`
+
+	// ConflictPolicy resolves keys found in more than one source. Defaults to first_wins.
+	ConflictPolicy catalog.ConflictPolicy `mapstructure:"conflict_policy"`
+
+	// SourceAttribute adds the backstage.source attribute naming the matched source.
+	SourceAttribute bool `mapstructure:"source_attribute"`
+
+	// AliasAnnotation names the entity annotation holding a comma-separated list of
+	// former service names, each of them registered as a lookup key of the entity.
+	AliasAnnotation string `mapstructure:"alias_annotation"`
+
+	// MatchStrategies are tried in order until one of them matches. Defaults to service_name.
+	MatchStrategies []MatchStrategy `mapstructure:"match_strategies"`
+
+	// Kubernetes configures the kubernetes match strategy.
+	Kubernetes KubernetesMatchConfig `mapstructure:"kubernetes"`
+
+	// FuzzyMatch configures the fallback tiers of the service_name strategy.
+	FuzzyMatch FuzzyMatchConfig `mapstructure:"fuzzy_match"`
+
+	// Spans configures the enrichment of span events and links.
+	Spans SpansConfig `mapstructure:"spans"`
+
+	// Peer enriches client spans with the entity of the dependency they call.
+	Peer PeerConfig `mapstructure:"peer"`
+
+	// Links adds the frontend page and the selected links of the matched entities.
+	Links LinksConfig `mapstructure:"links"`
+
+	// Metrics selects the metric data points enriched.
+	Metrics MetricsConfig `mapstructure:"metrics"`
+
+	// Cardinality caps the number of distinct values of the enriched data point attributes.
+	Cardinality CardinalityConfig `mapstructure:"cardinality"`
+
+	// Conditions are the OTTL conditions deciding which telemetry is enriched.
+	Conditions ConditionsConfig `mapstructure:"conditions"`
+
+	// ErrorMode decides how errors evaluating the conditions are handled: propagate returns
+	// them, ignore logs them and silent drops them. The telemetry whose conditions fail is left
+	// untouched, unless the error is propagated. Defaults to propagate.
+	ErrorMode ottl.ErrorMode `mapstructure:"error_mode"`
+
+	// Filter drops or marks telemetry according to the lifecycle, tags and labels of its entity.
+	Filter FilterConfig `mapstructure:"filter"`
+
+	// Sampling sets sampling hints on spans according to the tier of their entity.
+	Sampling SamplingConfig `mapstructure:"sampling"`
+
+	// Tenant resolves the tenant of the telemetry from its entity.
+	Tenant TenantConfig `mapstructure:"tenant"`
+
+	// Extension is the backstagecatalog extension providing the catalog. When set, the
+	// catalog is shared with other components and the inline catalog settings must be empty.
+	Extension *component.ID `mapstructure:"extension"`
+}
+
+// KubernetesMatchConfig defines how the kubernetes match strategy reads the resource attributes.
+type KubernetesMatchConfig struct {
+	// PodLabelPrefix is the prefix of the resource attributes holding the pod labels,
+	// as set by the k8sattributes processor. Defaults to k8s.pod.labels.
+	PodLabelPrefix string `mapstructure:"pod_label_prefix"`
+}
+
+// SpansConfig defines the enrichment of the attributes of span events and links. Events and
+// links are enriched when their attributes identify a service, by the match strategies or by
+// the peer.service attribute.
+type SpansConfig struct {
+	// Events enriches the attributes of span events, such as exceptions recorded by shared libraries.
+	Events bool `mapstructure:"events"`
+
+	// Links enriches the attributes of span links.
+	Links bool `mapstructure:"links"`
 }
 
 var _ component.Config = (*Config)(nil)
+
+// Validate checks if the processor configuration is valid.
+func (cfg *Config) Validate() error {
+	if err := cfg.validateEnrichment(); err != nil {
+		return err
+	}
+
+	catalogCfg := cfg.CatalogConfig()
+	if cfg.Extension != nil {
+		if !catalogCfg.IsEmpty() {
+			return errors.New("catalog settings must not be configured along with extension")
+		}
+		return nil
+	}
+	return catalogCfg.Validate()
+}
+
+// validateEnrichment checks the enrichment settings, every setting but the catalog ones. It is
+// shared by the processor and the Enricher.
+func (cfg *Config) validateEnrichment() error {
+	if err := cfg.validateMatchStrategies(); err != nil {
+		return err
+	}
+	if err := cfg.FuzzyMatch.Validate(); err != nil {
+		return err
+	}
+	if err := validateErrorMode(cfg.ErrorMode); err != nil {
+		return err
+	}
+	if err := cfg.Conditions.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Filter.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Sampling.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Tenant.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Links.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Metrics.Validate(); err != nil {
+		return err
+	}
+	return cfg.Cardinality.Validate()
+}
+
+// validateMatchStrategies checks that every configured match strategy is known.
+func (cfg *Config) validateMatchStrategies() error {
+	return cfg.matcher().Validate()
+}
+
+// CatalogConfig returns the inline catalog settings of the processor.
+func (cfg *Config) CatalogConfig() catalog.Config {
+	return catalog.Config{
+		Token:           cfg.Token,
+		Endpoint:        cfg.Endpoint,
+		RefreshInterval: cfg.RefreshInterval,
+		Namespace:       cfg.Namespace,
+		Filters:         cfg.Filters,
+		Sources:         cfg.Sources,
+		ConflictPolicy:  cfg.ConflictPolicy,
+		AliasAnnotation: cfg.AliasAnnotation,
+	}
+}
+
+// matcher returns the matcher of the configured match strategies.
+func (cfg *Config) matcher() Matcher {
+	return Matcher{Strategies: cfg.MatchStrategies, Kubernetes: cfg.Kubernetes}
+}
+
+// podLabelPrefix returns the configured pod label prefix, falling back to the k8sattributes one.
+func (cfg KubernetesMatchConfig) podLabelPrefix() string {
+	if cfg.PodLabelPrefix == "" {
+		return defaultPodLabelPrefix
+	}
+	return cfg.PodLabelPrefix
+}
@@ -2,8 +2,11 @@ package backstageprocessor
 
 import (
 	"testing"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
 )
 
 func TestConfigValidation(t *testing.T) {
@@ -25,3 +28,155 @@ func TestConfigValidation(t *testing.T) {
 		}
 	})
 }
+
+func TestConfigValidate(t *testing.T) {
+	extension := component.MustNewID("backstagecatalog")
+	invalidProbability := 1.5
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr string
+	}{
+		{
+			name:    "no endpoint nor sources",
+			config:  &Config{},
+			wantErr: "either endpoint or sources must be configured",
+		},
+		{
+			name:   "inline endpoint",
+			config: &Config{Endpoint: "https://backstage.example.com"},
+		},
+		{
+			name: "multiple sources",
+			config: &Config{
+				Sources: []catalog.SourceConfig{
+					{Name: "bu1", Endpoint: "https://bu1.example.com"},
+					{Name: "bu2", Endpoint: "https://bu2.example.com"},
+				},
+				ConflictPolicy: catalog.ConflictPolicyLastWins,
+			},
+		},
+		{
+			name: "invalid source",
+			config: &Config{
+				Sources: []catalog.SourceConfig{{Name: "bu1"}},
+			},
+			wantErr: `source "bu1": endpoint must not be empty`,
+		},
+		{
+			name: "unknown match strategy",
+			config: &Config{
+				Endpoint:        "https://backstage.example.com",
+				MatchStrategies: []MatchStrategy{MatchKubernetes, "guess"},
+			},
+			wantErr: `unknown match strategy "guess"`,
+		},
+		{
+			name: "unknown filter action",
+			config: &Config{
+				Endpoint: "https://backstage.example.com",
+				Filter:   FilterConfig{Action: "archive", Lifecycles: []string{"deprecated"}},
+			},
+			wantErr: `unknown filter action "archive"`,
+		},
+		{
+			name: "invalid sampling probability",
+			config: &Config{
+				Endpoint: "https://backstage.example.com",
+				Sampling: SamplingConfig{Tiers: map[string]TierSamplingConfig{"4": {Probability: &invalidProbability}}},
+			},
+			wantErr: `tier "4": probability must be within (0, 1]`,
+		},
+		{
+			name: "invalid metric name pattern",
+			config: &Config{
+				Endpoint: "https://backstage.example.com",
+				Metrics:  MetricsConfig{Include: []string{"["}},
+			},
+			wantErr: "invalid metrics.include pattern \"[\": error parsing regexp: missing closing ]: `[`",
+		},
+		{
+			name: "negative cardinality limit",
+			config: &Config{
+				Endpoint:    "https://backstage.example.com",
+				Cardinality: CardinalityConfig{MaxValues: -1},
+			},
+			wantErr: "cardinality.max_values must not be negative",
+		},
+		{
+			name: "relative frontend URL",
+			config: &Config{
+				Endpoint: "https://backstage.example.com",
+				Links:    LinksConfig{FrontendURL: "/catalog"},
+			},
+			wantErr: `links.frontend_url "/catalog" must be an absolute URL`,
+		},
+		{
+			name: "unknown error mode",
+			config: &Config{
+				Endpoint:  "https://backstage.example.com",
+				ErrorMode: "fail",
+			},
+			wantErr: `unknown error_mode "fail"`,
+		},
+		{
+			name: "unknown tenant field",
+			config: &Config{
+				Endpoint: "https://backstage.example.com",
+				Tenant:   TenantConfig{Field: "team", MetadataKey: "X-Scope-OrgID"},
+			},
+			wantErr: `unknown tenant field "team"`,
+		},
+		{
+			name:   "extension",
+			config: &Config{Extension: &extension},
+		},
+		{
+			name: "extension along with an endpoint",
+			config: &Config{
+				Endpoint:  "https://backstage.example.com",
+				Extension: &extension,
+			},
+			wantErr: "catalog settings must not be configured along with extension",
+		},
+		{
+			name: "extension along with filters",
+			config: &Config{
+				Filters:   []string{"kind=component"},
+				Extension: &extension,
+			},
+			wantErr: "catalog settings must not be configured along with extension",
+		},
+		{
+			name: "extension along with a refresh interval",
+			config: &Config{
+				RefreshInterval: time.Minute,
+				Extension:       &extension,
+			},
+			wantErr: "catalog settings must not be configured along with extension",
+		},
+		{
+			name: "extension along with an alias annotation",
+			config: &Config{
+				AliasAnnotation: "example.com/aliases",
+				Extension:       &extension,
+			},
+			wantErr: "catalog settings must not be configured along with extension",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got '%v'", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected error '%s', got '%v'", tt.wantErr, err)
+			}
+		})
+	}
+}
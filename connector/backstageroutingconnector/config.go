@@ -0,0 +1,120 @@
+package backstageroutingconnector
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pipeline"
+
+	backstageprocessor "github.com/v1v/opentelemetry-backstage-processor"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// RouteKey defines the entity field the telemetry is routed by.
+type RouteKey string
+
+const (
+	// RouteByOrg routes the telemetry by the org label of its entity.
+	RouteByOrg RouteKey = "org"
+	// RouteByDivision routes the telemetry by the division label of its entity.
+	RouteByDivision RouteKey = "division"
+	// RouteByOwner routes the telemetry by the spec.owner of its entity, the owning team,
+	// such as group:default/payments.
+	RouteByOwner RouteKey = "owner"
+)
+
+// Config defines configuration for the Backstage routing connector.
+type Config struct {
+	catalog.Config `mapstructure:",squash"`
+
+	// Extension is the backstagecatalog extension providing the catalog. When set, the
+	// catalog is shared with other components and the inline catalog settings must be empty.
+	Extension *component.ID `mapstructure:"extension"`
+
+	// MatchStrategies are tried in order until one of them matches. Defaults to service_name.
+	MatchStrategies []backstageprocessor.MatchStrategy `mapstructure:"match_strategies"`
+
+	// Kubernetes configures the kubernetes match strategy.
+	Kubernetes backstageprocessor.KubernetesMatchConfig `mapstructure:"kubernetes"`
+
+	// FuzzyMatch configures the fallback tiers of the service_name strategy, as for the processor.
+	FuzzyMatch backstageprocessor.FuzzyMatchConfig `mapstructure:"fuzzy_match"`
+
+	// RouteBy is the entity field compared with the route values. Defaults to org.
+	RouteBy RouteKey `mapstructure:"route_by"`
+
+	// Routes maps the org, division or owner of the entities to pipelines.
+	Routes []RouteConfig `mapstructure:"routes"`
+
+	// DefaultPipelines receive the telemetry matching no entity or no route. The
+	// telemetry is dropped when empty.
+	DefaultPipelines []pipeline.ID `mapstructure:"default_pipelines"`
+}
+
+// RouteConfig defines the pipelines receiving the telemetry of some orgs, divisions or owners.
+type RouteConfig struct {
+	// Values are the orgs, divisions or owners routed to the pipelines.
+	Values []string `mapstructure:"values"`
+
+	// Pipelines receive the telemetry of the entities with one of the values.
+	Pipelines []pipeline.ID `mapstructure:"pipelines"`
+}
+
+var _ component.Config = (*Config)(nil)
+
+// Validate checks if the connector configuration is valid.
+func (cfg *Config) Validate() error {
+	if err := cfg.matcher().Validate(); err != nil {
+		return err
+	}
+	if err := cfg.FuzzyMatch.Validate(); err != nil {
+		return err
+	}
+
+	switch cfg.RouteBy {
+	case "", RouteByOrg, RouteByDivision, RouteByOwner:
+	default:
+		return fmt.Errorf("unknown route_by %q", cfg.RouteBy)
+	}
+
+	if len(cfg.Routes) == 0 {
+		return errors.New("at least one route must be configured")
+	}
+	seen := map[string]bool{}
+	for i, route := range cfg.Routes {
+		if len(route.Values) == 0 {
+			return fmt.Errorf("routes[%d] has no values", i)
+		}
+		if len(route.Pipelines) == 0 {
+			return fmt.Errorf("routes[%d] has no pipelines", i)
+		}
+		for _, value := range route.Values {
+			if seen[value] {
+				return fmt.Errorf("value %q is routed more than once", value)
+			}
+			seen[value] = true
+		}
+	}
+
+	if cfg.Extension != nil {
+		if !cfg.Config.IsEmpty() {
+			return errors.New("catalog settings must not be configured along with extension")
+		}
+		return nil
+	}
+	return cfg.Config.Validate()
+}
+
+// matcher returns the matcher of the configured match strategies.
+func (cfg *Config) matcher() backstageprocessor.Matcher {
+	return backstageprocessor.Matcher{Strategies: cfg.MatchStrategies, Kubernetes: cfg.Kubernetes}
+}
+
+// routeBy returns the configured route key, falling back to org.
+func (cfg *Config) routeBy() RouteKey {
+	if cfg.RouteBy == "" {
+		return RouteByOrg
+	}
+	return cfg.RouteBy
+}
@@ -0,0 +1,98 @@
+package backstageroutingconnector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pipeline"
+
+	backstageprocessor "github.com/v1v/opentelemetry-backstage-processor"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestConfigValidate(t *testing.T) {
+	extension := component.MustNewID("backstagecatalog")
+	catalogCfg := catalog.Config{Endpoint: "https://backstage.example.com"}
+	routes := []RouteConfig{{Values: []string{"shop"}, Pipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalTraces, "shop")}}}
+
+	tests := []struct {
+		name        string
+		config      *Config
+		expectedErr string
+	}{
+		{
+			name:   "valid config",
+			config: &Config{Config: catalogCfg, Routes: routes},
+		},
+		{
+			name:   "valid config with extension",
+			config: &Config{Extension: &extension, RouteBy: RouteByDivision, Routes: routes},
+		},
+		{
+			name:   "valid config routing by owner",
+			config: &Config{Config: catalogCfg, RouteBy: RouteByOwner, Routes: routes},
+		},
+		{
+			name:        "extension along with an endpoint",
+			config:      &Config{Config: catalogCfg, Extension: &extension, Routes: routes},
+			expectedErr: "catalog settings must not be configured along with extension",
+		},
+		{
+			name:        "extension along with a namespace",
+			config:      &Config{Config: catalog.Config{Namespace: "platform"}, Extension: &extension, Routes: routes},
+			expectedErr: "catalog settings must not be configured along with extension",
+		},
+		{
+			name:        "invalid catalog config",
+			config:      &Config{Routes: routes},
+			expectedErr: "either endpoint or sources must be configured",
+		},
+		{
+			name:        "unknown route key",
+			config:      &Config{Config: catalogCfg, RouteBy: "team", Routes: routes},
+			expectedErr: `unknown route_by "team"`,
+		},
+		{
+			name:        "unknown match strategy",
+			config:      &Config{Config: catalogCfg, MatchStrategies: []backstageprocessor.MatchStrategy{"fuzzy"}, Routes: routes},
+			expectedErr: `unknown match strategy "fuzzy"`,
+		},
+		{
+			name:        "invalid fuzzy match",
+			config:      &Config{Config: catalogCfg, FuzzyMatch: backstageprocessor.FuzzyMatchConfig{MaxEditDistance: -1}, Routes: routes},
+			expectedErr: "fuzzy_match.max_edit_distance must not be negative",
+		},
+		{
+			name:        "no routes",
+			config:      &Config{Config: catalogCfg},
+			expectedErr: "at least one route must be configured",
+		},
+		{
+			name:        "route without values",
+			config:      &Config{Config: catalogCfg, Routes: []RouteConfig{{Pipelines: routes[0].Pipelines}}},
+			expectedErr: "routes[0] has no values",
+		},
+		{
+			name:        "route without pipelines",
+			config:      &Config{Config: catalogCfg, Routes: []RouteConfig{{Values: []string{"shop"}}}},
+			expectedErr: "routes[0] has no pipelines",
+		},
+		{
+			name:        "value routed twice",
+			config:      &Config{Config: catalogCfg, Routes: append(routes, routes[0])},
+			expectedErr: `value "shop" is routed more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
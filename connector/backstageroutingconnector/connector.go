@@ -0,0 +1,281 @@
+package backstageroutingconnector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.uber.org/zap"
+
+	backstageprocessor "github.com/v1v/opentelemetry-backstage-processor"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// backstageRouter resolves the route of resource attributes from the entity they match.
+// Routes are identified by their index in the configuration, the default route comes last.
+type backstageRouter struct {
+	logger  *zap.Logger
+	config  Config
+	matcher backstageprocessor.Matcher
+	catalog catalog.Provider // Set on Start when the catalog comes from an extension
+	inline  *catalog.Catalog // Catalog owned by the connector, nil when using an extension
+
+	// routes indexes the routes by org or division
+	routes map[string]int
+}
+
+func newBackstageRouter(set component.TelemetrySettings, config component.Config) (*backstageRouter, error) {
+	cfg := config.(*Config)
+
+	r := &backstageRouter{
+		logger:  set.Logger,
+		config:  *cfg,
+		matcher: cfg.matcher(),
+		catalog: &catalog.Snapshot{},
+		routes:  map[string]int{},
+	}
+	r.matcher.Fuzzy = backstageprocessor.NewFuzzyMatcher(cfg.FuzzyMatch)
+	for i, route := range cfg.Routes {
+		for _, value := range route.Values {
+			r.routes[value] = i
+		}
+	}
+
+	if cfg.Extension == nil {
+		inline, err := catalog.New(set, cfg.Config)
+		if err != nil {
+			return nil, err
+		}
+		r.inline = inline
+		r.catalog = inline
+	}
+	return r, nil
+}
+
+// Start fetches the inline catalog, or looks up the backstagecatalog extension
+func (r *backstageRouter) Start(ctx context.Context, host component.Host) error {
+	if r.inline != nil {
+		return r.inline.Start(ctx)
+	}
+
+	ext, found := host.GetExtensions()[*r.config.Extension]
+	if !found {
+		return fmt.Errorf("backstage catalog extension %q not found", r.config.Extension.String())
+	}
+	provider, ok := ext.(catalog.Provider)
+	if !ok {
+		return fmt.Errorf("extension %q is not a backstage catalog", r.config.Extension.String())
+	}
+	r.catalog = provider
+	return nil
+}
+
+// Shutdown stops the inline catalog refresh loop if running
+func (r *backstageRouter) Shutdown(ctx context.Context) error {
+	if r.inline != nil {
+		return r.inline.Shutdown(ctx)
+	}
+	return nil
+}
+
+// Capabilities returns the consumer capabilities, the incoming data is copied to the routes.
+func (r *backstageRouter) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{MutatesData: false}
+}
+
+// defaultRoute returns the index of the default route
+func (r *backstageRouter) defaultRoute() int {
+	return len(r.config.Routes)
+}
+
+// route returns the index of the route of the resource attributes
+func (r *backstageRouter) route(snapshot *catalog.Snapshot, attributes pcommon.Map) int {
+	info, _, matched := r.matcher.Match(snapshot, attributes)
+	if !matched {
+		return r.defaultRoute()
+	}
+
+	var value string
+	switch r.config.routeBy() {
+	case RouteByOrg:
+		value = info.Org
+	case RouteByDivision:
+		value = info.Division
+	case RouteByOwner:
+		value = info.Owner
+	}
+	if i, ok := r.routes[value]; ok {
+		return i
+	}
+	return r.defaultRoute()
+}
+
+// pipelines returns the pipelines of every route followed by the default ones
+func (r *backstageRouter) pipelines() [][]pipeline.ID {
+	pipelines := make([][]pipeline.ID, 0, len(r.config.Routes)+1)
+	for _, route := range r.config.Routes {
+		pipelines = append(pipelines, route.Pipelines)
+	}
+	return append(pipelines, r.config.DefaultPipelines)
+}
+
+type tracesConnector struct {
+	*backstageRouter
+	consumers []consumer.Traces // nil for the default route without pipelines
+}
+
+var _ connector.Traces = (*tracesConnector)(nil)
+
+func newTracesConnector(r *backstageRouter, router connector.TracesRouterAndConsumer) (*tracesConnector, error) {
+	c := &tracesConnector{backstageRouter: r}
+	for _, pipelines := range r.pipelines() {
+		if len(pipelines) == 0 {
+			c.consumers = append(c.consumers, nil)
+			continue
+		}
+		next, err := router.Consumer(pipelines...)
+		if err != nil {
+			return nil, err
+		}
+		c.consumers = append(c.consumers, next)
+	}
+	return c, nil
+}
+
+// ConsumeTraces splits the batch by resource and sends every part to its route
+func (c *tracesConnector) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	snapshot := c.catalog.Snapshot()
+	batches := map[int]ptrace.Traces{}
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		route := c.route(snapshot, rs.Resource().Attributes())
+		if c.consumers[route] == nil {
+			c.logger.Debug("Dropping unrouted spans", zap.Any("resource", rs.Resource().Attributes().AsRaw()))
+			continue
+		}
+		batch, ok := batches[route]
+		if !ok {
+			batch = ptrace.NewTraces()
+			batches[route] = batch
+		}
+		rs.CopyTo(batch.ResourceSpans().AppendEmpty())
+	}
+
+	var errs []error
+	for route, next := range c.consumers {
+		if batch, ok := batches[route]; ok {
+			errs = append(errs, next.ConsumeTraces(ctx, batch))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type logsConnector struct {
+	*backstageRouter
+	consumers []consumer.Logs // nil for the default route without pipelines
+}
+
+var _ connector.Logs = (*logsConnector)(nil)
+
+func newLogsConnector(r *backstageRouter, router connector.LogsRouterAndConsumer) (*logsConnector, error) {
+	c := &logsConnector{backstageRouter: r}
+	for _, pipelines := range r.pipelines() {
+		if len(pipelines) == 0 {
+			c.consumers = append(c.consumers, nil)
+			continue
+		}
+		next, err := router.Consumer(pipelines...)
+		if err != nil {
+			return nil, err
+		}
+		c.consumers = append(c.consumers, next)
+	}
+	return c, nil
+}
+
+// ConsumeLogs splits the batch by resource and sends every part to its route
+func (c *logsConnector) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	snapshot := c.catalog.Snapshot()
+	batches := map[int]plog.Logs{}
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		route := c.route(snapshot, rl.Resource().Attributes())
+		if c.consumers[route] == nil {
+			c.logger.Debug("Dropping unrouted logs", zap.Any("resource", rl.Resource().Attributes().AsRaw()))
+			continue
+		}
+		batch, ok := batches[route]
+		if !ok {
+			batch = plog.NewLogs()
+			batches[route] = batch
+		}
+		rl.CopyTo(batch.ResourceLogs().AppendEmpty())
+	}
+
+	var errs []error
+	for route, next := range c.consumers {
+		if batch, ok := batches[route]; ok {
+			errs = append(errs, next.ConsumeLogs(ctx, batch))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type metricsConnector struct {
+	*backstageRouter
+	consumers []consumer.Metrics // nil for the default route without pipelines
+}
+
+var _ connector.Metrics = (*metricsConnector)(nil)
+
+func newMetricsConnector(r *backstageRouter, router connector.MetricsRouterAndConsumer) (*metricsConnector, error) {
+	c := &metricsConnector{backstageRouter: r}
+	for _, pipelines := range r.pipelines() {
+		if len(pipelines) == 0 {
+			c.consumers = append(c.consumers, nil)
+			continue
+		}
+		next, err := router.Consumer(pipelines...)
+		if err != nil {
+			return nil, err
+		}
+		c.consumers = append(c.consumers, next)
+	}
+	return c, nil
+}
+
+// ConsumeMetrics splits the batch by resource and sends every part to its route
+func (c *metricsConnector) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	snapshot := c.catalog.Snapshot()
+	batches := map[int]pmetric.Metrics{}
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		route := c.route(snapshot, rm.Resource().Attributes())
+		if c.consumers[route] == nil {
+			c.logger.Debug("Dropping unrouted metrics", zap.Any("resource", rm.Resource().Attributes().AsRaw()))
+			continue
+		}
+		batch, ok := batches[route]
+		if !ok {
+			batch = pmetric.NewMetrics()
+			batches[route] = batch
+		}
+		rm.CopyTo(batch.ResourceMetrics().AppendEmpty())
+	}
+
+	var errs []error
+	for route, next := range c.consumers {
+		if batch, ok := batches[route]; ok {
+			errs = append(errs, next.ConsumeMetrics(ctx, batch))
+		}
+	}
+	return errors.Join(errs...)
+}
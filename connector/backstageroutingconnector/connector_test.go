@@ -0,0 +1,243 @@
+package backstageroutingconnector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/connector/connectortest"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+
+	backstageprocessor "github.com/v1v/opentelemetry-backstage-processor"
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// newTestConfig returns a config routing the shop and payments orgs to their own
+// pipelines, with the rest of the retail division routed together
+func newTestConfig(t *testing.T, signal pipeline.Signal) *Config {
+	server := backstagetest.NewServer(t,
+		backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop", "division": "retail"}),
+		backstagetest.GithubRepository("", "cart", "acme/cart", map[string]string{"org": "shop", "division": "retail"}),
+		backstagetest.GithubRepository("", "billing", "acme/billing", map[string]string{"org": "payments", "division": "finance"}),
+		backstagetest.GithubRepository("", "search", "acme/search", map[string]string{"org": "discovery", "division": "retail"}),
+	)
+	return &Config{
+		Config: catalog.Config{Endpoint: server.URL},
+		Routes: []RouteConfig{
+			{Values: []string{"shop"}, Pipelines: []pipeline.ID{pipeline.NewIDWithName(signal, "shop")}},
+			{Values: []string{"payments"}, Pipelines: []pipeline.ID{pipeline.NewIDWithName(signal, "payments")}},
+		},
+		DefaultPipelines: []pipeline.ID{pipeline.NewIDWithName(signal, "default")},
+	}
+}
+
+func startConnector(t *testing.T, c component.Component) {
+	require.NoError(t, c.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { assert.NoError(t, c.Shutdown(context.Background())) })
+}
+
+func TestTracesRouting(t *testing.T) {
+	shop, payments, fallback := new(consumertest.TracesSink), new(consumertest.TracesSink), new(consumertest.TracesSink)
+	router := connector.NewTracesRouter(map[pipeline.ID]consumer.Traces{
+		pipeline.NewIDWithName(pipeline.SignalTraces, "shop"):     shop,
+		pipeline.NewIDWithName(pipeline.SignalTraces, "payments"): payments,
+		pipeline.NewIDWithName(pipeline.SignalTraces, "default"):  fallback,
+	})
+
+	factory := NewFactory()
+	conn, err := factory.CreateTracesToTraces(context.Background(), connectortest.NewNopSettings(factory.Type()), newTestConfig(t, pipeline.SignalTraces), router)
+	require.NoError(t, err)
+	startConnector(t, conn)
+
+	traces := ptrace.NewTraces()
+	for _, service := range []string{"acme-checkout", "acme-billing", "acme-cart", "acme-search", "unknown"} {
+		rs := traces.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", service)
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName(service)
+	}
+	require.NoError(t, conn.ConsumeTraces(context.Background(), traces))
+
+	resources := func(sink *consumertest.TracesSink) []any {
+		require.Len(t, sink.AllTraces(), 1, "a single batch per route")
+		rss := sink.AllTraces()[0].ResourceSpans()
+		names := []any{}
+		for i := 0; i < rss.Len(); i++ {
+			names = append(names, rss.At(i).Resource().Attributes().AsRaw()["service.name"])
+		}
+		return names
+	}
+	assert.Equal(t, []any{"acme-checkout", "acme-cart"}, resources(shop))
+	assert.Equal(t, []any{"acme-billing"}, resources(payments))
+	assert.Equal(t, []any{"acme-search", "unknown"}, resources(fallback))
+	assert.Equal(t, 5, traces.SpanCount(), "the incoming batch is left untouched")
+}
+
+func TestLogsRoutingByDivision(t *testing.T) {
+	retail, finance := new(consumertest.LogsSink), new(consumertest.LogsSink)
+	router := connector.NewLogsRouter(map[pipeline.ID]consumer.Logs{
+		pipeline.NewIDWithName(pipeline.SignalLogs, "retail"):  retail,
+		pipeline.NewIDWithName(pipeline.SignalLogs, "finance"): finance,
+	})
+
+	cfg := newTestConfig(t, pipeline.SignalLogs)
+	cfg.RouteBy = RouteByDivision
+	cfg.Routes = []RouteConfig{
+		{Values: []string{"retail"}, Pipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalLogs, "retail")}},
+		{Values: []string{"finance"}, Pipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalLogs, "finance")}},
+	}
+	cfg.DefaultPipelines = nil
+
+	factory := NewFactory()
+	conn, err := factory.CreateLogsToLogs(context.Background(), connectortest.NewNopSettings(factory.Type()), cfg, router)
+	require.NoError(t, err)
+	startConnector(t, conn)
+
+	logs := plog.NewLogs()
+	for _, service := range []string{"acme-search", "acme-billing", "unknown"} {
+		rl := logs.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().PutStr("service.name", service)
+		rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(service)
+	}
+	require.NoError(t, conn.ConsumeLogs(context.Background(), logs))
+
+	assert.Equal(t, 1, retail.LogRecordCount())
+	assert.Equal(t, 1, finance.LogRecordCount())
+	assert.Equal(t, "acme-billing", finance.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+}
+
+func TestTracesRoutingByOwner(t *testing.T) {
+	payments, fallback := new(consumertest.TracesSink), new(consumertest.TracesSink)
+	router := connector.NewTracesRouter(map[pipeline.ID]consumer.Traces{
+		pipeline.NewIDWithName(pipeline.SignalTraces, "payments"): payments,
+		pipeline.NewIDWithName(pipeline.SignalTraces, "default"):  fallback,
+	})
+
+	billing := backstagetest.GithubRepository("", "billing", "acme/billing", map[string]string{"org": "payments"})
+	billing.Spec["owner"] = "group:default/payments-team"
+	invoices := backstagetest.GithubRepository("", "invoices", "acme/invoices", map[string]string{"org": "payments"})
+	invoices.Spec["owner"] = "group:default/invoicing-team"
+	server := backstagetest.NewServer(t, billing, invoices)
+	cfg := &Config{
+		Config:           catalog.Config{Endpoint: server.URL},
+		RouteBy:          RouteByOwner,
+		Routes:           []RouteConfig{{Values: []string{"group:default/payments-team"}, Pipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalTraces, "payments")}}},
+		DefaultPipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalTraces, "default")},
+	}
+
+	factory := NewFactory()
+	conn, err := factory.CreateTracesToTraces(context.Background(), connectortest.NewNopSettings(factory.Type()), cfg, router)
+	require.NoError(t, err)
+	startConnector(t, conn)
+
+	traces := ptrace.NewTraces()
+	for _, service := range []string{"acme-billing", "acme-invoices"} {
+		rs := traces.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", service)
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName(service)
+	}
+	require.NoError(t, conn.ConsumeTraces(context.Background(), traces))
+
+	require.Equal(t, 1, payments.SpanCount())
+	assert.Equal(t, "acme-billing", payments.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name())
+	require.Equal(t, 1, fallback.SpanCount(), "entities of the same org with another owner aren't routed")
+	assert.Equal(t, "acme-invoices", fallback.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name())
+}
+
+func TestTracesRoutingFuzzyMatch(t *testing.T) {
+	payments, fallback := new(consumertest.TracesSink), new(consumertest.TracesSink)
+	router := connector.NewTracesRouter(map[pipeline.ID]consumer.Traces{
+		pipeline.NewIDWithName(pipeline.SignalTraces, "payments"): payments,
+		pipeline.NewIDWithName(pipeline.SignalTraces, "default"):  fallback,
+	})
+
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "billing", "acme/billing", map[string]string{"org": "payments"}))
+	cfg := &Config{
+		Config:           catalog.Config{Endpoint: server.URL},
+		FuzzyMatch:       backstageprocessor.FuzzyMatchConfig{CaseInsensitive: true, NormalizeSeparators: true},
+		Routes:           []RouteConfig{{Values: []string{"payments"}, Pipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalTraces, "payments")}}},
+		DefaultPipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalTraces, "default")},
+	}
+
+	factory := NewFactory()
+	conn, err := factory.CreateTracesToTraces(context.Background(), connectortest.NewNopSettings(factory.Type()), cfg, router)
+	require.NoError(t, err)
+	startConnector(t, conn)
+
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "Acme_Billing")
+	rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("Acme_Billing")
+	require.NoError(t, conn.ConsumeTraces(context.Background(), traces))
+
+	assert.Equal(t, 1, payments.SpanCount(), "the service name is fuzzy matched as by the processor")
+	assert.Zero(t, fallback.SpanCount())
+}
+
+func TestMetricsRoutingWithExtension(t *testing.T) {
+	shop, fallback := new(consumertest.MetricsSink), new(consumertest.MetricsSink)
+	router := connector.NewMetricsRouter(map[pipeline.ID]consumer.Metrics{
+		pipeline.NewIDWithName(pipeline.SignalMetrics, "shop"):    shop,
+		pipeline.NewIDWithName(pipeline.SignalMetrics, "default"): fallback,
+	})
+
+	extension := component.MustNewID("backstagecatalog")
+	cfg := &Config{
+		Extension:        &extension,
+		Routes:           []RouteConfig{{Values: []string{"shop"}, Pipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalMetrics, "shop")}}},
+		DefaultPipelines: []pipeline.ID{pipeline.NewIDWithName(pipeline.SignalMetrics, "default")},
+	}
+
+	factory := NewFactory()
+	conn, err := factory.CreateMetricsToMetrics(context.Background(), connectortest.NewNopSettings(factory.Type()), cfg, router)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, conn.Start(context.Background(), componenttest.NewNopHost()), "not found")
+
+	snapshot, err := catalog.NewSnapshot(map[string]catalog.EntityInfo{
+		"acme-checkout": {Org: "shop", EntityRef: "resource:default/checkout"},
+	})
+	require.NoError(t, err)
+	host := testHost{extensions: map[component.ID]component.Component{
+		extension: testCatalogExtension{Provider: snapshot},
+	}}
+	require.NoError(t, conn.Start(context.Background(), host))
+	defer func() { assert.NoError(t, conn.Shutdown(context.Background())) }()
+
+	metrics := pmetric.NewMetrics()
+	for _, service := range []string{"acme-checkout", "unknown"} {
+		rm := metrics.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("service.name", service)
+		rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(1)
+	}
+	require.NoError(t, conn.ConsumeMetrics(context.Background(), metrics))
+
+	assert.Equal(t, 1, shop.DataPointCount())
+	assert.Equal(t, 1, fallback.DataPointCount())
+}
+
+// testCatalogExtension is a backstagecatalog extension serving a fixed snapshot
+type testCatalogExtension struct {
+	component.StartFunc
+	component.ShutdownFunc
+	catalog.Provider
+}
+
+// testHost is a host holding the given extensions
+type testHost struct {
+	component.Host
+	extensions map[component.ID]component.Component
+}
+
+func (h testHost) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
@@ -0,0 +1,91 @@
+package backstageroutingconnector
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector"
+	"go.opentelemetry.io/collector/consumer"
+)
+
+const (
+	LogsStability    = component.StabilityLevelAlpha
+	MetricsStability = component.StabilityLevelAlpha
+	TracesStability  = component.StabilityLevelAlpha
+)
+
+// errNotRouter is returned when the next consumer doesn't route to pipelines, which only
+// happens outside of a collector pipeline.
+var errNotRouter = errors.New("expected the next consumer to be a connector router")
+
+// Note: This isn't a valid configuration because the connector would route nothing.
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
+
+// NewFactory returns a new factory for the Backstage routing connector.
+func NewFactory() connector.Factory {
+	return connector.NewFactory(
+		component.MustNewType("backstagerouting"),
+		createDefaultConfig,
+		connector.WithTracesToTraces(createTracesToTraces, TracesStability),
+		connector.WithLogsToLogs(createLogsToLogs, LogsStability),
+		connector.WithMetricsToMetrics(createMetricsToMetrics, MetricsStability))
+}
+
+func createTracesToTraces(
+	_ context.Context,
+	set connector.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Traces,
+) (connector.Traces, error) {
+
+	router, ok := nextConsumer.(connector.TracesRouterAndConsumer)
+	if !ok {
+		return nil, errNotRouter
+	}
+	// every signal loads its own copy of the catalog, use the backstagecatalog
+	// extension to share a single one.
+	r, err := newBackstageRouter(set.TelemetrySettings, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newTracesConnector(r, router)
+}
+
+func createLogsToLogs(
+	_ context.Context,
+	set connector.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Logs,
+) (connector.Logs, error) {
+
+	router, ok := nextConsumer.(connector.LogsRouterAndConsumer)
+	if !ok {
+		return nil, errNotRouter
+	}
+	r, err := newBackstageRouter(set.TelemetrySettings, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newLogsConnector(r, router)
+}
+
+func createMetricsToMetrics(
+	_ context.Context,
+	set connector.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (connector.Metrics, error) {
+
+	router, ok := nextConsumer.(connector.MetricsRouterAndConsumer)
+	if !ok {
+		return nil, errNotRouter
+	}
+	r, err := newBackstageRouter(set.TelemetrySettings, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newMetricsConnector(r, router)
+}
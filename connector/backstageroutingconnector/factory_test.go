@@ -0,0 +1,39 @@
+package backstageroutingconnector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/connector/connectortest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+)
+
+func TestNewFactory(t *testing.T) {
+	factory := NewFactory()
+
+	assert.Equal(t, component.MustNewType("backstagerouting"), factory.Type())
+	assert.Equal(t, component.StabilityLevelAlpha, factory.TracesToTracesStability())
+	assert.Equal(t, component.StabilityLevelAlpha, factory.LogsToLogsStability())
+	assert.Equal(t, component.StabilityLevelAlpha, factory.MetricsToMetricsStability())
+}
+
+func TestCreateDefaultConfig(t *testing.T) {
+	cfg, ok := createDefaultConfig().(*Config)
+	require.True(t, ok, "Expected config to be of type *Config")
+
+	assert.Empty(t, cfg.Routes)
+	assert.Equal(t, RouteByOrg, cfg.routeBy())
+	assert.Error(t, cfg.Validate(), "default config has no routes")
+}
+
+func TestCreateConnectorRequiresRouter(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = "https://backstage.example.com"
+
+	_, err := factory.CreateTracesToTraces(context.Background(), connectortest.NewNopSettings(factory.Type()), cfg, consumertest.NewNop())
+	assert.ErrorIs(t, err, errNotRouter)
+}
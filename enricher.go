@@ -0,0 +1,168 @@
+package backstageprocessor
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// Enricher applies the enrichment of the processor outside of a collector pipeline,
+// against a given catalog, and records how the telemetry matched it. The catalog
+// settings of the configuration are ignored.
+type Enricher struct {
+	processor *backstageprocessor
+	report    map[serviceOutcome]int
+	total     MatchReport
+}
+
+// serviceOutcome groups the matches reported together
+type serviceOutcome struct {
+	ServiceMatch
+	matched bool
+}
+
+// MatchReport summarizes the matches of the telemetry enriched by an Enricher. Attribute
+// sets are the resource, span, log record and data point attributes processed.
+type MatchReport struct {
+	// Matched counts the attribute sets matching an entity.
+	Matched int `json:"matched"`
+	// Unmatched counts the attribute sets identifying a service that matches no entity.
+	Unmatched int `json:"unmatched"`
+	// Unidentified counts the attribute sets without any of the attributes used to match.
+	Unidentified int `json:"unidentified"`
+	// Services lists the outcome of every identified service, unmatched ones first.
+	Services []ServiceReport `json:"services"`
+}
+
+// ServiceMatch is the outcome of matching the attributes of a service.
+type ServiceMatch struct {
+	ServiceName string `json:"service.name"`
+	EntityRef   string `json:"entityRef,omitempty"`
+	Org         string `json:"org,omitempty"`
+	Division    string `json:"division,omitempty"`
+	Source      string `json:"source,omitempty"`
+}
+
+// ServiceReport counts the attribute sets of a service with the same outcome.
+type ServiceReport struct {
+	ServiceMatch
+	Matched bool `json:"matched"`
+	Count   int  `json:"count"`
+}
+
+// NewEnricher returns an enricher matching telemetry with the configured strategies
+// against the given catalog.
+func NewEnricher(logger *zap.Logger, cfg *Config, provider catalog.Provider) (*Enricher, error) {
+	if err := cfg.validateEnrichment(); err != nil {
+		return nil, err
+	}
+	metrics, err := cfg.Metrics.selector()
+	if err != nil {
+		return nil, err
+	}
+	set := component.TelemetrySettings{Logger: logger, MeterProvider: noop.NewMeterProvider()}
+	telemetry, err := newProcessorTelemetry(set)
+	if err != nil {
+		return nil, err
+	}
+	conditions, err := newEnrichConditions(cfg.Conditions, cfg.ErrorMode, set)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Enricher{report: map[serviceOutcome]int{}}
+	e.processor = &backstageprocessor{
+		logger:      logger,
+		config:      *cfg,
+		catalog:     provider,
+		telemetry:   telemetry,
+		metrics:     metrics,
+		cardinality: newCardinalityLimiter(cfg.Cardinality, logger, telemetry),
+		conditions:  conditions,
+		fuzzy:       NewFuzzyMatcher(cfg.FuzzyMatch),
+		observe:     e.observe,
+	}
+	return e, nil
+}
+
+// Traces enriches the traces in place, removing the spans dropped by the filter policy.
+func (e *Enricher) Traces(ctx context.Context, td ptrace.Traces) error {
+	_, err := e.processor.processTraces(ctx, td)
+	return skipped(err)
+}
+
+// Logs enriches the logs in place, removing the log records dropped by the filter policy.
+func (e *Enricher) Logs(ctx context.Context, ld plog.Logs) error {
+	_, err := e.processor.processLogs(ctx, ld)
+	return skipped(err)
+}
+
+// Metrics enriches the metrics in place, removing the data points dropped by the filter policy.
+func (e *Enricher) Metrics(ctx context.Context, md pmetric.Metrics) error {
+	_, err := e.processor.processMetrics(ctx, md)
+	return skipped(err)
+}
+
+// skipped ignores the error returned when the filter policy drops a whole batch
+func skipped(err error) error {
+	if errors.Is(err, processorhelper.ErrSkipProcessingData) {
+		return nil
+	}
+	return err
+}
+
+// Report returns the matches recorded so far.
+func (e *Enricher) Report() MatchReport {
+	report := e.total
+	report.Services = []ServiceReport{}
+	for outcome, count := range e.report {
+		report.Services = append(report.Services, ServiceReport{ServiceMatch: outcome.ServiceMatch, Matched: outcome.matched, Count: count})
+	}
+	sort.Slice(report.Services, func(i, j int) bool {
+		a, b := report.Services[i], report.Services[j]
+		if a.Matched != b.Matched {
+			return !a.Matched
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return a.EntityRef < b.EntityRef
+	})
+	return report
+}
+
+// observe records the outcome of a match
+func (e *Enricher) observe(attributes pcommon.Map, info catalog.EntityInfo, identified bool, matched bool) {
+	switch {
+	case !identified:
+		e.total.Unidentified++
+		return
+	case matched:
+		e.total.Matched++
+	default:
+		e.total.Unmatched++
+	}
+
+	outcome := serviceOutcome{matched: matched}
+	if serviceName, found := attributes.Get(serviceNameKey); found {
+		outcome.ServiceName = serviceName.Str()
+	}
+	if matched {
+		outcome.EntityRef = info.EntityRef
+		outcome.Org = info.Org
+		outcome.Division = info.Division
+		outcome.Source = info.Source
+	}
+	e.report[outcome]++
+}
@@ -0,0 +1,63 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestEnricher(t *testing.T) {
+	snapshot := &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{
+		"acme-checkout": {Org: "shop", Division: "retail", Source: "default", EntityRef: "resource:default/checkout"},
+	}}
+
+	t.Run("enriches traces and reports the matches", func(t *testing.T) {
+		enricher, err := NewEnricher(zap.NewNop(), &Config{}, snapshot)
+		require.NoError(t, err)
+
+		td := ptrace.NewTraces()
+		for _, name := range []string{"acme-checkout", "acme-checkout", "legacy"} {
+			rs := td.ResourceSpans().AppendEmpty()
+			rs.Resource().Attributes().PutStr(serviceNameKey, name)
+			rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+		}
+		require.NoError(t, enricher.Traces(context.Background(), td))
+
+		org, _ := td.ResourceSpans().At(0).Resource().Attributes().Get(orgKey)
+		assert.Equal(t, "shop", org.Str())
+		org, _ = td.ResourceSpans().At(2).Resource().Attributes().Get(orgKey)
+		assert.Equal(t, unknown, org.Str())
+
+		assert.Equal(t, MatchReport{
+			Matched:      2,
+			Unmatched:    1,
+			Unidentified: 3,
+			Services: []ServiceReport{
+				{ServiceMatch: ServiceMatch{ServiceName: "legacy"}, Count: 1},
+				{
+					ServiceMatch: ServiceMatch{ServiceName: "acme-checkout", EntityRef: "resource:default/checkout", Org: "shop", Division: "retail", Source: "default"},
+					Matched:      true,
+					Count:        2,
+				},
+			},
+		}, enricher.Report())
+	})
+
+	t.Run("rejects unknown match strategies", func(t *testing.T) {
+		_, err := NewEnricher(zap.NewNop(), &Config{MatchStrategies: []MatchStrategy{"unknown"}}, snapshot)
+		assert.EqualError(t, err, `unknown match strategy "unknown"`)
+	})
+
+	t.Run("validates the enrichment settings as the processor", func(t *testing.T) {
+		_, err := NewEnricher(zap.NewNop(), &Config{Links: LinksConfig{FrontendURL: "backstage.example.com"}}, snapshot)
+		assert.EqualError(t, err, `links.frontend_url "backstage.example.com" must be an absolute URL`)
+		_, err = NewEnricher(zap.NewNop(), &Config{Cardinality: CardinalityConfig{MaxValues: -1}}, snapshot)
+		assert.EqualError(t, err, "cardinality.max_values must not be negative")
+	})
+}
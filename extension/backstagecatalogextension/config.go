@@ -0,0 +1,55 @@
+package backstagecatalogextension
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configopaque"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// Config defines configuration for the Backstage catalog extension.
+type Config struct {
+	catalog.Config `mapstructure:",squash"`
+
+	// Debug configures the opt-in HTTP endpoint exposing the loaded catalog.
+	Debug DebugConfig `mapstructure:"debug"`
+}
+
+// DebugConfig defines the debug HTTP endpoint of the extension.
+type DebugConfig struct {
+	// Endpoint is the address the debug endpoint listens on, e.g. localhost:55690.
+	// The endpoint is disabled when empty.
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Refresh configures the authenticated endpoint triggering an immediate refresh.
+	Refresh RefreshConfig `mapstructure:"refresh"`
+}
+
+// RefreshConfig defines the refresh endpoint of the extension.
+type RefreshConfig struct {
+	// Token is the bearer token the refresh requests must carry. The endpoint is
+	// disabled when empty.
+	Token configopaque.String `mapstructure:"token"`
+
+	// MinInterval is the minimum time between two triggered refreshes. Defaults to 30s.
+	MinInterval time.Duration `mapstructure:"min_interval"`
+}
+
+var _ component.Config = (*Config)(nil)
+
+// Validate checks if the extension configuration is valid.
+func (cfg *Config) Validate() error {
+	if err := cfg.Config.Validate(); err != nil {
+		return err
+	}
+	if cfg.Debug.Refresh.Token != "" && cfg.Debug.Endpoint == "" {
+		return errors.New("debug.refresh.token requires debug.endpoint to be set")
+	}
+	if cfg.Debug.Refresh.MinInterval < 0 {
+		return errors.New("debug.refresh.min_interval must not be negative")
+	}
+	return nil
+}
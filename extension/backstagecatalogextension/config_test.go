@@ -0,0 +1,65 @@
+package backstagecatalogextension
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Config
+		expectedErr string
+	}{
+		{
+			name:   "valid config without debug endpoint",
+			config: &Config{Config: catalog.Config{Endpoint: "https://backstage.example.com"}},
+		},
+		{
+			name: "valid config with refresh endpoint",
+			config: &Config{
+				Config: catalog.Config{Endpoint: "https://backstage.example.com"},
+				Debug: DebugConfig{
+					Endpoint: "localhost:55690",
+					Refresh:  RefreshConfig{Token: "admin-token", MinInterval: time.Minute},
+				},
+			},
+		},
+		{
+			name:        "invalid catalog config",
+			config:      &Config{},
+			expectedErr: "either endpoint or sources must be configured",
+		},
+		{
+			name: "refresh token without debug endpoint",
+			config: &Config{
+				Config: catalog.Config{Endpoint: "https://backstage.example.com"},
+				Debug:  DebugConfig{Refresh: RefreshConfig{Token: "admin-token"}},
+			},
+			expectedErr: "debug.refresh.token requires debug.endpoint to be set",
+		},
+		{
+			name: "negative refresh min interval",
+			config: &Config{
+				Config: catalog.Config{Endpoint: "https://backstage.example.com"},
+				Debug:  DebugConfig{Refresh: RefreshConfig{MinInterval: -time.Second}},
+			},
+			expectedErr: "debug.refresh.min_interval must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
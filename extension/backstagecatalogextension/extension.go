@@ -0,0 +1,129 @@
+package backstagecatalogextension
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// catalogExtension owns a catalog shared by every component referencing the extension.
+// Components look it up in the host extensions and use it through catalog.Provider.
+type catalogExtension struct {
+	*catalog.Catalog
+
+	id     component.ID
+	debug  DebugConfig
+	logger *zap.Logger
+	server *http.Server
+}
+
+var (
+	_ extension.Extension = (*catalogExtension)(nil)
+	_ catalog.Provider    = (*catalogExtension)(nil)
+)
+
+// Start fetches the catalog, starts its background refresh and the debug endpoints, if enabled
+func (e *catalogExtension) Start(ctx context.Context, _ component.Host) error {
+	if err := e.Catalog.Start(ctx); err != nil {
+		return err
+	}
+	if e.debug.Endpoint != "" {
+		if err := e.startDebugEndpoint(); err != nil {
+			return errors.Join(err, e.Catalog.Shutdown(ctx))
+		}
+	}
+	// the catalog is only made available to the other components once the extension started
+	register(e.id, e.Catalog)
+	return nil
+}
+
+// startDebugEndpoint serves the debug and refresh handlers of the catalog
+func (e *catalogExtension) startDebugEndpoint() error {
+	listener, err := net.Listen("tcp", e.debug.Endpoint)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", catalog.NewDebugHandler(e.Catalog))
+	if refresh := e.debug.Refresh; refresh.Token != "" {
+		mux.Handle("/refresh", catalog.NewRefreshHandler(e.Catalog, refresh.Token, refresh.MinInterval))
+	}
+	e.server = &http.Server{Addr: listener.Addr().String(), Handler: mux}
+	e.logger.Info("Starting the catalog debug endpoint", zap.String("endpoint", e.server.Addr))
+	go func() {
+		if err := e.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.logger.Error("Catalog debug endpoint failed", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the debug endpoint and the background refresh
+func (e *catalogExtension) Shutdown(ctx context.Context) error {
+	var errs []error
+	if e.server != nil {
+		errs = append(errs, e.server.Shutdown(ctx))
+	}
+	unregister(e.id, e.Catalog)
+	errs = append(errs, e.Catalog.Shutdown(ctx))
+	return errors.Join(errs...)
+}
+
+var (
+	registryMu sync.RWMutex
+	// registry holds the catalogs of the started extensions, by extension ID
+	registry = map[component.ID]*catalog.Catalog{}
+)
+
+// register makes the catalog of a started extension available to Provider
+func register(id component.ID, c *catalog.Catalog) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[id] = c
+}
+
+// unregister removes the catalog of an extension being shut down, unless another
+// instance of the extension replaced it
+func unregister(id component.ID, c *catalog.Catalog) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if registry[id] == c {
+		delete(registry, id)
+	}
+}
+
+// Provider returns the catalog of the backstagecatalog extension with the given ID, for the
+// code that has no access to the host extensions, such as OTTL functions. The catalog is
+// resolved on every lookup, and is empty while the extension isn't started.
+func Provider(id component.ID) catalog.Provider {
+	return registeredProvider{id: id}
+}
+
+// registeredProvider resolves the catalog of an extension in the registry
+type registeredProvider struct {
+	id component.ID
+}
+
+var _ catalog.Provider = registeredProvider{}
+
+func (p registeredProvider) Lookup(key string) (catalog.EntityInfo, bool) {
+	return p.Snapshot().Lookup(key)
+}
+
+func (p registeredProvider) Snapshot() *catalog.Snapshot {
+	registryMu.RLock()
+	c, ok := registry[p.id]
+	registryMu.RUnlock()
+	if !ok {
+		return &catalog.Snapshot{}
+	}
+	return c.Snapshot()
+}
@@ -0,0 +1,117 @@
+package backstagecatalogextension
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/extension/extensiontest"
+
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestCatalogExtension(t *testing.T) {
+	server := backstagetest.NewServer(t,
+		backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop", "division": "retail"}),
+	)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL
+
+	ext, err := factory.Create(context.Background(), extensiontest.NewNopSettings(factory.Type()), cfg)
+	require.NoError(t, err)
+
+	provider, ok := ext.(catalog.Provider)
+	require.True(t, ok, "Expected the extension to be a catalog provider")
+
+	require.NoError(t, ext.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, ext.Shutdown(context.Background())) }()
+
+	info, found := provider.Lookup("acme-checkout")
+	require.True(t, found)
+	assert.Equal(t, "shop", info.Org)
+	assert.Equal(t, "retail", info.Division)
+
+	snapshot, err := json.Marshal(provider.Snapshot().Keys["resource:default/checkout"])
+	require.NoError(t, err)
+	assert.Contains(t, string(snapshot), `"entityRef":"resource:default/checkout"`)
+}
+
+func TestCatalogExtensionDebugEndpoint(t *testing.T) {
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop"}))
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL
+	cfg.Token = "secret-token"
+	cfg.Debug.Endpoint = "localhost:0"
+
+	ext, err := factory.Create(context.Background(), extensiontest.NewNopSettings(factory.Type()), cfg)
+	require.NoError(t, err)
+	require.NoError(t, ext.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, ext.Shutdown(context.Background())) }()
+
+	debugURL := "http://" + ext.(*catalogExtension).server.Addr
+	resp, err := http.Get(debugURL + "/resolve?service.name=acme-checkout")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"rule": "repository"`)
+	assert.NotContains(t, string(body), "secret-token")
+}
+
+func TestProvider(t *testing.T) {
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop"}))
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL
+
+	set := extensiontest.NewNopSettings(factory.Type())
+	provider := Provider(set.ID)
+	_, found := provider.Lookup("acme-checkout")
+	assert.False(t, found, "Expected an empty catalog before the extension starts")
+
+	ext, err := factory.Create(context.Background(), set, cfg)
+	require.NoError(t, err)
+	require.NoError(t, ext.Start(context.Background(), componenttest.NewNopHost()))
+
+	info, found := provider.Lookup("acme-checkout")
+	require.True(t, found)
+	assert.Equal(t, "shop", info.Org)
+
+	require.NoError(t, ext.Shutdown(context.Background()))
+	_, found = provider.Lookup("acme-checkout")
+	assert.False(t, found, "Expected an empty catalog after the extension shuts down")
+}
+
+func TestProviderFailedStart(t *testing.T) {
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop"}))
+	busy, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL
+	cfg.Debug.Endpoint = busy.Addr().String()
+
+	set := extensiontest.NewNopSettings(factory.Type())
+	ext, err := factory.Create(context.Background(), set, cfg)
+	require.NoError(t, err)
+	require.Error(t, ext.Start(context.Background(), componenttest.NewNopHost()))
+
+	_, found := Provider(set.ID).Lookup("acme-checkout")
+	assert.False(t, found, "Expected an empty catalog when the extension fails to start")
+}
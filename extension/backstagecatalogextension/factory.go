@@ -0,0 +1,48 @@
+package backstagecatalogextension
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+const ExtensionStability = component.StabilityLevelAlpha
+
+// defaultRefreshMinInterval is the default minimum time between two triggered refreshes.
+const defaultRefreshMinInterval = 30 * time.Second
+
+// Note: This isn't a valid configuration because the extension would load no entities.
+func createDefaultConfig() component.Config {
+	return &Config{
+		Debug: DebugConfig{
+			Refresh: RefreshConfig{MinInterval: defaultRefreshMinInterval},
+		},
+	}
+}
+
+// NewFactory returns a new factory for the Backstage catalog extension.
+func NewFactory() extension.Factory {
+	return extension.NewFactory(
+		component.MustNewType("backstagecatalog"),
+		createDefaultConfig,
+		createExtension,
+		ExtensionStability)
+}
+
+func createExtension(
+	_ context.Context,
+	set extension.Settings,
+	cfg component.Config,
+) (extension.Extension, error) {
+
+	extCfg := cfg.(*Config)
+	c, err := catalog.New(set.TelemetrySettings, extCfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	return &catalogExtension{Catalog: c, id: set.ID, debug: extCfg.Debug, logger: set.Logger}, nil
+}
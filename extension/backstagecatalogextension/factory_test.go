@@ -0,0 +1,40 @@
+package backstagecatalogextension
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/extensiontest"
+)
+
+func TestNewFactory(t *testing.T) {
+	factory := NewFactory()
+
+	assert.Equal(t, component.MustNewType("backstagecatalog"), factory.Type())
+	assert.Equal(t, component.StabilityLevelAlpha, factory.Stability())
+}
+
+func TestCreateDefaultConfig(t *testing.T) {
+	cfg, ok := createDefaultConfig().(*Config)
+	require.True(t, ok, "Expected config to be of type *Config")
+
+	assert.Empty(t, cfg.Endpoint)
+	assert.Empty(t, cfg.Sources)
+	assert.Empty(t, cfg.Debug.Endpoint)
+	assert.Equal(t, defaultRefreshMinInterval, cfg.Debug.Refresh.MinInterval)
+	assert.Error(t, cfg.Validate(), "default config has no endpoint nor sources")
+}
+
+func TestCreateExtension(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = "https://backstage.example.com"
+	cfg.Token = "test-token"
+
+	ext, err := factory.Create(context.Background(), extensiontest.NewNopSettings(factory.Type()), cfg)
+	require.NoError(t, err)
+	require.NotNil(t, ext)
+}
@@ -5,14 +5,19 @@ import (
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/xconsumer"
 	"go.opentelemetry.io/collector/processor"
 	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/collector/processor/processorhelper/xprocessorhelper"
+	"go.opentelemetry.io/collector/processor/xprocessor"
 )
 
 const (
 	LogsStability    = component.StabilityLevelAlpha
 	MetricsStability = component.StabilityLevelAlpha
 	TracesStability  = component.StabilityLevelAlpha
+	// ProfilesStability follows the development stability of the profiles signal.
+	ProfilesStability = component.StabilityLevelDevelopment
 )
 
 var processorCapabilities = consumer.Capabilities{MutatesData: true}
@@ -24,12 +29,13 @@ func createDefaultConfig() component.Config {
 
 // NewFactory returns a new factory for the Attributes processor.
 func NewFactory() processor.Factory {
-	return processor.NewFactory(
+	return xprocessor.NewFactory(
 		component.MustNewType("backstageprocessor"),
 		createDefaultConfig,
-		processor.WithMetrics(createMetricsProcessor, MetricsStability),
-		processor.WithLogs(createLogsProcessor, LogsStability),
-		processor.WithTraces(createTracesProcessor, TracesStability))
+		xprocessor.WithMetrics(createMetricsProcessor, MetricsStability),
+		xprocessor.WithLogs(createLogsProcessor, LogsStability),
+		xprocessor.WithTraces(createTracesProcessor, TracesStability),
+		xprocessor.WithProfiles(createProfilesProcessor, ProfilesStability))
 }
 
 func createTracesProcessor(
@@ -39,10 +45,15 @@ func createTracesProcessor(
 	nextConsumer consumer.Traces,
 ) (processor.Traces, error) {
 
-	// need to find out how we can create the maps with the labels once
-	// rather than one per type of processor as it is done in the createLogsProcessor
-	// function below.
-	processor := newBackstageProcessor(set.Logger, cfg)
+	// every signal loads its own copy of the catalog, use the backstagecatalog
+	// extension to share a single one.
+	processor, err := newBackstageProcessor(set.TelemetrySettings, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tenant := cfg.(*Config).Tenant; tenant.MetadataKey != "" {
+		nextConsumer = tenantTraces{Traces: nextConsumer, config: tenant}
+	}
 	return processorhelper.NewTraces(
 		ctx,
 		set,
@@ -50,6 +61,7 @@ func createTracesProcessor(
 		nextConsumer,
 		processor.processTraces,
 		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithStart(processor.Start),
 		processorhelper.WithShutdown(processor.Shutdown))
 }
 
@@ -60,7 +72,13 @@ func createLogsProcessor(
 	nextLogsConsumer consumer.Logs,
 ) (processor.Logs, error) {
 
-	processor := newBackstageProcessor(set.Logger, cfg)
+	processor, err := newBackstageProcessor(set.TelemetrySettings, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tenant := cfg.(*Config).Tenant; tenant.MetadataKey != "" {
+		nextLogsConsumer = tenantLogs{Logs: nextLogsConsumer, config: tenant}
+	}
 	return processorhelper.NewLogs(
 		ctx,
 		set,
@@ -68,6 +86,7 @@ func createLogsProcessor(
 		nextLogsConsumer,
 		processor.processLogs,
 		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithStart(processor.Start),
 		processorhelper.WithShutdown(processor.Shutdown))
 }
 
@@ -78,8 +97,14 @@ func createMetricsProcessor(
 	nextConsumer consumer.Metrics,
 ) (processor.Metrics, error) {
 
-	processor := newBackstageProcessor(set.Logger, cfg)
+	processor, err := newBackstageProcessor(set.TelemetrySettings, cfg)
+	if err != nil {
+		return nil, err
+	}
 
+	if tenant := cfg.(*Config).Tenant; tenant.MetadataKey != "" {
+		nextConsumer = tenantMetrics{Metrics: nextConsumer, config: tenant}
+	}
 	return processorhelper.NewMetrics(
 		ctx,
 		set,
@@ -87,5 +112,32 @@ func createMetricsProcessor(
 		nextConsumer,
 		processor.processMetrics,
 		processorhelper.WithCapabilities(processorCapabilities),
+		processorhelper.WithStart(processor.Start),
 		processorhelper.WithShutdown(processor.Shutdown))
 }
+
+func createProfilesProcessor(
+	ctx context.Context,
+	set processor.Settings,
+	cfg component.Config,
+	nextConsumer xconsumer.Profiles,
+) (xprocessor.Profiles, error) {
+
+	processor, err := newBackstageProcessor(set.TelemetrySettings, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if tenant := cfg.(*Config).Tenant; tenant.MetadataKey != "" {
+		nextConsumer = tenantProfiles{Profiles: nextConsumer, config: tenant}
+	}
+	return xprocessorhelper.NewProfiles(
+		ctx,
+		set,
+		cfg,
+		nextConsumer,
+		processor.processProfiles,
+		xprocessorhelper.WithCapabilities(processorCapabilities),
+		xprocessorhelper.WithStart(processor.Start),
+		xprocessorhelper.WithShutdown(processor.Shutdown))
+}
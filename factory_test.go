@@ -7,6 +7,7 @@ import (
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer/consumertest"
 	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/collector/processor/xprocessor"
 )
 
 func TestNewFactory(t *testing.T) {
@@ -101,6 +102,32 @@ func TestCreateMetricsProcessor(t *testing.T) {
 	}
 }
 
+func TestCreateProfilesProcessor(t *testing.T) {
+	factory, ok := NewFactory().(xprocessor.Factory)
+	if !ok {
+		t.Fatal("Expected factory to support profiles")
+	}
+	cfg := factory.CreateDefaultConfig()
+	backstageCfg := cfg.(*Config)
+	backstageCfg.Endpoint = "https://backstage.example.com"
+	backstageCfg.Token = "test-token"
+
+	set := processortest.NewNopSettings(component.MustNewType("backstageprocessor"))
+	nextConsumer := consumertest.NewNop()
+
+	pp, err := factory.CreateProfiles(context.Background(), set, cfg, nextConsumer)
+	if err != nil {
+		t.Fatalf("CreateProfilesProcessor failed: %v", err)
+	}
+
+	if pp == nil {
+		t.Fatal("Expected profiles processor to be created")
+	}
+	if factory.ProfilesStability() != ProfilesStability {
+		t.Errorf("Expected profiles stability to be %s, got %s", ProfilesStability, factory.ProfilesStability())
+	}
+}
+
 func TestProcessorCapabilities(t *testing.T) {
 	if !processorCapabilities.MutatesData {
 		t.Error("Expected processor to mutate data")
@@ -117,4 +144,7 @@ func TestStabilityLevels(t *testing.T) {
 	if TracesStability != component.StabilityLevelAlpha {
 		t.Errorf("Expected traces stability to be Alpha, got %s", TracesStability)
 	}
+	if ProfilesStability != component.StabilityLevelDevelopment {
+		t.Errorf("Expected profiles stability to be Development, got %s", ProfilesStability)
+	}
 }
@@ -0,0 +1,121 @@
+package backstageprocessor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// FilterAction defines what happens to the telemetry selected by the filter policy.
+type FilterAction string
+
+const (
+	// FilterActionDrop removes the selected telemetry.
+	FilterActionDrop FilterAction = "drop"
+	// FilterActionMark keeps the selected telemetry and sets the filter attribute to the
+	// reason it was selected, so that it can be routed separately.
+	FilterActionMark FilterAction = "mark"
+)
+
+// defaultFilterAttribute is the attribute set by the mark action.
+const defaultFilterAttribute = "backstage.filtered"
+
+// reasons the filter policy selects telemetry for
+const (
+	filterReasonUnmatched = "unmatched"
+	filterReasonLifecycle = "lifecycle"
+	filterReasonTag       = "tag"
+	filterReasonLabel     = "label"
+)
+
+// FilterConfig selects the telemetry to drop or mark according to its matched entity.
+// Telemetry is selected when its entity has any of the configured lifecycles, tags or
+// labels. Attributes without any of the attributes used to match are never selected.
+type FilterConfig struct {
+	// Action is drop or mark. Defaults to drop.
+	Action FilterAction `mapstructure:"action"`
+
+	// Attribute is the attribute set by the mark action. Defaults to backstage.filtered.
+	Attribute string `mapstructure:"attribute"`
+
+	// Lifecycles selects the entities with one of these spec.lifecycle values, e.g. deprecated.
+	Lifecycles []string `mapstructure:"lifecycles"`
+
+	// Tags selects the entities with one of these tags.
+	Tags []string `mapstructure:"tags"`
+
+	// Labels selects the entities with one of these label values.
+	Labels map[string]string `mapstructure:"labels"`
+
+	// Unmatched selects the telemetry identifying a service that matches no entity.
+	Unmatched bool `mapstructure:"unmatched"`
+}
+
+// Validate checks if the filter configuration is valid.
+func (cfg *FilterConfig) Validate() error {
+	switch cfg.Action {
+	case "", FilterActionDrop, FilterActionMark:
+	default:
+		return fmt.Errorf("unknown filter action %q", cfg.Action)
+	}
+	return nil
+}
+
+// enabled reports whether the filter selects any telemetry
+func (cfg *FilterConfig) enabled() bool {
+	return cfg.Unmatched || len(cfg.Lifecycles) > 0 || len(cfg.Tags) > 0 || len(cfg.Labels) > 0
+}
+
+// action returns the configured action, falling back to drop.
+func (cfg *FilterConfig) action() FilterAction {
+	if cfg.Action == "" {
+		return FilterActionDrop
+	}
+	return cfg.Action
+}
+
+// attribute returns the configured mark attribute, falling back to backstage.filtered.
+func (cfg *FilterConfig) attribute() string {
+	if cfg.Attribute == "" {
+		return defaultFilterAttribute
+	}
+	return cfg.Attribute
+}
+
+// reason returns why the filter selects the outcome of a match, or an empty string when
+// it is kept as is. Reasons are unmatched, lifecycle:<lifecycle>, tag:<tag> or label:<key>=<value>.
+func (cfg *FilterConfig) reason(info catalog.EntityInfo, identified bool, matched bool) string {
+	if !identified {
+		return ""
+	}
+	if !matched {
+		if cfg.Unmatched {
+			return filterReasonUnmatched
+		}
+		return ""
+	}
+
+	if info.Lifecycle != "" && slices.ContainsFunc(cfg.Lifecycles, func(l string) bool { return strings.EqualFold(l, info.Lifecycle) }) {
+		return filterReasonLifecycle + ":" + strings.ToLower(info.Lifecycle)
+	}
+	for _, tag := range cfg.Tags {
+		if slices.Contains(info.Tags, tag) {
+			return filterReasonTag + ":" + tag
+		}
+	}
+
+	// labels are checked in key order so that the reason is deterministic
+	keys := make([]string, 0, len(cfg.Labels))
+	for key := range cfg.Labels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if value, ok := info.Labels[key]; ok && value == cfg.Labels[key] {
+			return filterReasonLabel + ":" + key + "=" + value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,230 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestFilterReason(t *testing.T) {
+	cfg := FilterConfig{
+		Lifecycles: []string{"Deprecated", "experimental"},
+		Tags:       []string{"noisy"},
+		Labels:     map[string]string{"tier": "sandbox", "env": "dev"},
+		Unmatched:  true,
+	}
+
+	tests := []struct {
+		name       string
+		info       catalog.EntityInfo
+		identified bool
+		matched    bool
+		expected   string
+	}{
+		{name: "unidentified", identified: false, expected: ""},
+		{name: "unmatched", identified: true, expected: filterReasonUnmatched},
+		{name: "lifecycle", info: catalog.EntityInfo{Lifecycle: "deprecated"}, identified: true, matched: true, expected: "lifecycle:deprecated"},
+		{name: "tag", info: catalog.EntityInfo{Lifecycle: "production", Tags: []string{"java", "noisy"}}, identified: true, matched: true, expected: "tag:noisy"},
+		{name: "label", info: catalog.EntityInfo{Labels: map[string]string{"tier": "sandbox", "env": "dev"}}, identified: true, matched: true, expected: "label:env=dev"},
+		{name: "other label value", info: catalog.EntityInfo{Labels: map[string]string{"tier": "critical"}}, identified: true, matched: true, expected: ""},
+		{name: "kept", info: catalog.EntityInfo{Lifecycle: "production"}, identified: true, matched: true, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, cfg.reason(tt.info, tt.identified, tt.matched))
+		})
+	}
+
+	t.Run("unmatched telemetry is kept by default", func(t *testing.T) {
+		cfg := FilterConfig{Lifecycles: []string{"deprecated"}}
+		assert.Empty(t, cfg.reason(catalog.EntityInfo{}, true, false))
+	})
+}
+
+// newFilterProcessor returns a processor with the given filter policy, reporting its
+// metrics to the returned telemetry
+func newFilterProcessor(t *testing.T, filter FilterConfig) (*backstageprocessor, *componenttest.Telemetry) {
+	t.Helper()
+	tel := componenttest.NewTelemetry()
+	t.Cleanup(func() { _ = tel.Shutdown(context.Background()) })
+	telemetry, err := newProcessorTelemetry(tel.NewTelemetrySettings())
+	require.NoError(t, err)
+
+	return &backstageprocessor{
+		logger: zap.NewNop(),
+		config: Config{Filter: filter},
+		catalog: &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{
+			"checkout": {Org: "shop", EntityRef: "component:default/checkout", Lifecycle: "production"},
+			"legacy":   {Org: "shop", EntityRef: "component:default/legacy", Lifecycle: "deprecated"},
+		}},
+		telemetry: telemetry,
+	}, tel
+}
+
+// assertDropped checks the dropped items counted for a signal
+func assertDropped(t *testing.T, tel *componenttest.Telemetry, signal string, expected int64) {
+	t.Helper()
+	got, err := tel.GetMetric("otelcol_backstage_processor_dropped_items")
+	require.NoError(t, err)
+	sum, ok := got.Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, expected, sum.DataPoints[0].Value)
+	value, _ := sum.DataPoints[0].Attributes.Value(attribute.Key("signal"))
+	assert.Equal(t, signal, value.AsString())
+}
+
+func TestFilterTraces(t *testing.T) {
+	newTraces := func() ptrace.Traces {
+		td := ptrace.NewTraces()
+		for _, name := range []string{"checkout", "legacy"} {
+			rs := td.ResourceSpans().AppendEmpty()
+			rs.Resource().Attributes().PutStr(serviceNameKey, name)
+			spans := rs.ScopeSpans().AppendEmpty().Spans()
+			spans.AppendEmpty().SetName("span1")
+			spans.AppendEmpty().SetName("span2")
+		}
+		// a span of the checkout resource reporting a deprecated peer service
+		td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(1).Attributes().PutStr(serviceNameKey, "legacy")
+		return td
+	}
+
+	t.Run("drops the selected resources and spans", func(t *testing.T) {
+		processor, tel := newFilterProcessor(t, FilterConfig{Lifecycles: []string{"deprecated"}})
+
+		td, err := processor.processTraces(context.Background(), newTraces())
+		require.NoError(t, err)
+		require.Equal(t, 1, td.ResourceSpans().Len())
+		assert.Equal(t, 1, td.SpanCount())
+		assert.Equal(t, "span1", td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name())
+		assertDropped(t, tel, signalTraces, 3)
+	})
+
+	t.Run("marks the selected telemetry", func(t *testing.T) {
+		processor, _ := newFilterProcessor(t, FilterConfig{Action: FilterActionMark, Lifecycles: []string{"deprecated"}})
+
+		td, err := processor.processTraces(context.Background(), newTraces())
+		require.NoError(t, err)
+		assert.Equal(t, 4, td.SpanCount())
+
+		_, marked := td.ResourceSpans().At(0).Resource().Attributes().Get(defaultFilterAttribute)
+		assert.False(t, marked)
+		reason, _ := td.ResourceSpans().At(1).Resource().Attributes().Get(defaultFilterAttribute)
+		assert.Equal(t, "lifecycle:deprecated", reason.Str())
+	})
+
+	t.Run("skips the rest of the pipeline when everything is dropped", func(t *testing.T) {
+		processor, _ := newFilterProcessor(t, FilterConfig{Lifecycles: []string{"deprecated", "production"}})
+
+		_, err := processor.processTraces(context.Background(), newTraces())
+		assert.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	})
+}
+
+func TestFilterLogs(t *testing.T) {
+	processor, tel := newFilterProcessor(t, FilterConfig{Unmatched: true})
+
+	ld := plog.NewLogs()
+	for _, name := range []string{"checkout", "unknown-service"} {
+		rl := ld.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().PutStr(serviceNameKey, name)
+		rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("message")
+	}
+
+	ld, err := processor.processLogs(context.Background(), ld)
+	require.NoError(t, err)
+	require.Equal(t, 1, ld.ResourceLogs().Len())
+	name, _ := ld.ResourceLogs().At(0).Resource().Attributes().Get(serviceNameKey)
+	assert.Equal(t, "checkout", name.Str())
+	assertDropped(t, tel, signalLogs, 1)
+}
+
+func TestFilterMetrics(t *testing.T) {
+	processor, tel := newFilterProcessor(t, FilterConfig{Lifecycles: []string{"deprecated"}})
+
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	metrics := rm.ScopeMetrics().AppendEmpty().Metrics()
+	gauge := metrics.AppendEmpty()
+	gauge.SetName("calls")
+	dps := gauge.SetEmptyGauge().DataPoints()
+	for _, name := range []string{"checkout", "legacy"} {
+		dps.AppendEmpty().Attributes().PutStr(serviceNameKey, name)
+	}
+	histogram := metrics.AppendEmpty()
+	histogram.SetName("latency")
+	histogram.SetEmptyHistogram().DataPoints().AppendEmpty().Attributes().PutStr(serviceNameKey, "legacy")
+
+	md, err := processor.processMetrics(context.Background(), md)
+	require.NoError(t, err)
+	require.Equal(t, 1, metrics.Len(), "metrics without data points left are removed")
+	assert.Equal(t, "calls", metrics.At(0).Name())
+	require.Equal(t, 1, metrics.At(0).Gauge().DataPoints().Len())
+	assert.Equal(t, 1, md.DataPointCount())
+	assertDropped(t, tel, signalMetrics, 2)
+}
+
+func TestFilterMetricsSelection(t *testing.T) {
+	processor, tel := newFilterProcessor(t, FilterConfig{Lifecycles: []string{"deprecated"}})
+	processor.config.Metrics = MetricsConfig{Include: []string{"^calls$"}}
+	selector, err := processor.config.Metrics.selector()
+	require.NoError(t, err)
+	processor.metrics = selector
+
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	for _, name := range []string{"calls", "latency"} {
+		metric := metrics.AppendEmpty()
+		metric.SetName(name)
+		dps := metric.SetEmptyGauge().DataPoints()
+		for _, service := range []string{"checkout", "legacy"} {
+			dps.AppendEmpty().Attributes().PutStr(serviceNameKey, service)
+		}
+	}
+
+	_, err = processor.processMetrics(context.Background(), md)
+	require.NoError(t, err)
+	require.Equal(t, 2, metrics.Len())
+	for i := 0; i < metrics.Len(); i++ {
+		require.Equal(t, 1, metrics.At(i).Gauge().DataPoints().Len(), "the filter applies to every metric")
+	}
+	_, enriched := metrics.At(0).Gauge().DataPoints().At(0).Attributes().Get(orgKey)
+	assert.True(t, enriched)
+	assert.Equal(t, map[string]any{serviceNameKey: "checkout"}, metrics.At(1).Gauge().DataPoints().At(0).Attributes().AsRaw(),
+		"the metrics selection only restricts the enrichment")
+	assertDropped(t, tel, signalMetrics, 2)
+}
+
+func TestFilterProfiles(t *testing.T) {
+	processor, tel := newFilterProcessor(t, FilterConfig{Lifecycles: []string{"deprecated"}})
+
+	pd := newProfile(t, "checkout", "checkout")
+	samples := pd.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0).Samples()
+	legacy := samples.AppendEmpty()
+	attrs := pcommon.NewMap()
+	attrs.PutStr(serviceNameKey, "legacy")
+	require.NoError(t, putSampleAttributes(pd.Dictionary(), legacy, attrs))
+	newProfile(t, "legacy", "legacy").ResourceProfiles().At(0).CopyTo(pd.ResourceProfiles().AppendEmpty())
+
+	pd, err := processor.processProfiles(context.Background(), pd)
+	require.NoError(t, err)
+	require.Equal(t, 1, pd.ResourceProfiles().Len())
+	assert.Equal(t, 1, pd.SampleCount())
+	assertDropped(t, tel, signalProfiles, 2)
+
+	_, err = processor.processProfiles(context.Background(), newProfile(t, "legacy", "checkout"))
+	assert.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+}
@@ -0,0 +1,303 @@
+package backstageprocessor
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// defaultFuzzyCacheSize is the default number of service names whose fuzzy match is cached.
+const defaultFuzzyCacheSize = 10000
+
+// matchTypeKey is the attribute recording the tier matching the service name
+const matchTypeKey = "backstage.match.type"
+
+// MatchType is the tier of the service_name strategy matching a service name.
+type MatchType string
+
+const (
+	// MatchTypeExact matches a lookup key or an entity reference as is. The other match
+	// strategies always report exact matches.
+	MatchTypeExact MatchType = "exact"
+	// MatchTypeCaseInsensitive matches a lookup key regardless of the case.
+	MatchTypeCaseInsensitive MatchType = "case_insensitive"
+	// MatchTypeSeparators matches a lookup key regardless of the case and of the _, . and - separators.
+	MatchTypeSeparators MatchType = "separators"
+	// MatchTypeAffixes matches a lookup key once the configured prefixes and suffixes are stripped.
+	MatchTypeAffixes MatchType = "affixes"
+	// MatchTypeEditDistance matches the closest lookup key within the maximum edit distance.
+	MatchTypeEditDistance MatchType = "edit_distance"
+)
+
+// FuzzyMatchConfig defines the fallback tiers of the service_name strategy, tried in order when
+// the service name matches no lookup key as is. Every tier is disabled by default.
+type FuzzyMatchConfig struct {
+	// CaseInsensitive matches the lookup keys regardless of the case, Org-Repo matching org-repo.
+	CaseInsensitive bool `mapstructure:"case_insensitive"`
+
+	// NormalizeSeparators matches the lookup keys regardless of the case and of the _, . and -
+	// separators, org_repo matching org-repo.
+	NormalizeSeparators bool `mapstructure:"normalize_separators"`
+
+	// StripPrefixes and StripSuffixes are removed from the service name before matching it
+	// again, org-repo-service matching org-repo with the -service suffix.
+	StripPrefixes []string `mapstructure:"strip_prefixes"`
+	StripSuffixes []string `mapstructure:"strip_suffixes"`
+
+	// MaxEditDistance matches the closest lookup key within that number of edits, as long as
+	// no other entity is as close. Disabled when 0.
+	MaxEditDistance int `mapstructure:"max_edit_distance"`
+
+	// CacheSize is the number of service names whose match is cached until the catalog is
+	// refreshed. Defaults to 10000.
+	CacheSize int `mapstructure:"cache_size"`
+}
+
+// Validate checks if the fuzzy match configuration is valid.
+func (cfg *FuzzyMatchConfig) Validate() error {
+	if cfg.MaxEditDistance < 0 {
+		return errors.New("fuzzy_match.max_edit_distance must not be negative")
+	}
+	if cfg.CacheSize < 0 {
+		return errors.New("fuzzy_match.cache_size must not be negative")
+	}
+	return nil
+}
+
+// enabled reports whether any fallback tier is enabled.
+func (cfg *FuzzyMatchConfig) enabled() bool {
+	return cfg.CaseInsensitive || cfg.NormalizeSeparators || len(cfg.StripPrefixes) > 0 ||
+		len(cfg.StripSuffixes) > 0 || cfg.MaxEditDistance > 0
+}
+
+// cacheSize returns the configured cache size, falling back to 10000.
+func (cfg *FuzzyMatchConfig) cacheSize() int {
+	if cfg.CacheSize == 0 {
+		return defaultFuzzyCacheSize
+	}
+	return cfg.CacheSize
+}
+
+// normalize folds a name as the strongest enabled tier compares it
+func (cfg *FuzzyMatchConfig) normalize(name string) string {
+	switch {
+	case cfg.NormalizeSeparators:
+		return separatorReplacer.Replace(strings.ToLower(name))
+	case cfg.CaseInsensitive:
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// separatorReplacer replaces the separators of service names by -
+var separatorReplacer = strings.NewReplacer("_", "-", ".", "-")
+
+// FuzzyMatcher looks up the service names missing the exact lookup with the fallback tiers,
+// caching the outcome of every name until the catalog snapshot changes. It is safe for
+// concurrent use.
+type FuzzyMatcher struct {
+	config FuzzyMatchConfig
+
+	mu       sync.Mutex
+	snapshot *catalog.Snapshot
+	index    *fuzzyIndex
+	results  map[string]fuzzyResult
+}
+
+// fuzzyResult is the cached outcome of a service name
+type fuzzyResult struct {
+	info      catalog.EntityInfo
+	matchType MatchType
+	matched   bool
+}
+
+// NewFuzzyMatcher returns the fuzzy matcher of the configuration, nil when no tier is enabled.
+func NewFuzzyMatcher(cfg FuzzyMatchConfig) *FuzzyMatcher {
+	if !cfg.enabled() {
+		return nil
+	}
+	return &FuzzyMatcher{config: cfg}
+}
+
+// Lookup matches a service name missing the exact lookup against the snapshot, returning
+// the tier that matched.
+func (f *FuzzyMatcher) Lookup(snapshot *catalog.Snapshot, name string) (catalog.EntityInfo, MatchType, bool) {
+	f.mu.Lock()
+	if f.snapshot != snapshot || len(f.results) >= f.config.cacheSize() {
+		if f.snapshot != snapshot {
+			f.snapshot = snapshot
+			f.index = newFuzzyIndex(&f.config, snapshot)
+		}
+		f.results = map[string]fuzzyResult{}
+	}
+	if result, ok := f.results[name]; ok {
+		f.mu.Unlock()
+		return result.info, result.matchType, result.matched
+	}
+	index := f.index
+	f.mu.Unlock()
+
+	// the index is never modified once built, the edit distances are computed without the lock
+	// for the lookups of other names not to wait on them
+	var result fuzzyResult
+	result.info, result.matchType, result.matched = index.lookup(&f.config, name)
+
+	f.mu.Lock()
+	if f.snapshot == snapshot && len(f.results) < f.config.cacheSize() {
+		f.results[name] = result
+	}
+	f.mu.Unlock()
+	return result.info, result.matchType, result.matched
+}
+
+// fuzzyIndex indexes the lookup keys of a snapshot by their folded forms
+type fuzzyIndex struct {
+	folded     map[string]fuzzyEntry
+	normalized map[string]fuzzyEntry
+	// keys are the normalized keys, sorted for the edit distance to be deterministic
+	keys []string
+}
+
+// fuzzyEntry is the entity of a folded key, ambiguous when keys of different entities fold alike
+type fuzzyEntry struct {
+	info      catalog.EntityInfo
+	ambiguous bool
+}
+
+// newFuzzyIndex indexes the keys of the snapshot for the enabled tiers
+func newFuzzyIndex(cfg *FuzzyMatchConfig, snapshot *catalog.Snapshot) *fuzzyIndex {
+	index := &fuzzyIndex{normalized: map[string]fuzzyEntry{}}
+	if cfg.CaseInsensitive {
+		index.folded = map[string]fuzzyEntry{}
+	}
+	for key, info := range snapshot.Keys {
+		if index.folded != nil {
+			addFuzzyEntry(index.folded, strings.ToLower(key), info)
+		}
+		addFuzzyEntry(index.normalized, cfg.normalize(key), info)
+	}
+	for key := range index.normalized {
+		index.keys = append(index.keys, key)
+	}
+	sort.Strings(index.keys)
+	return index
+}
+
+// addFuzzyEntry adds an entity under a folded key, marking the key ambiguous when it is
+// already taken by another entity
+func addFuzzyEntry(entries map[string]fuzzyEntry, key string, info catalog.EntityInfo) {
+	entry, ok := entries[key]
+	switch {
+	case !ok:
+		entries[key] = fuzzyEntry{info: info}
+	case entry.info.EntityRef != info.EntityRef:
+		entry.ambiguous = true
+		entries[key] = entry
+	}
+}
+
+// lookupEntry returns the entity of a folded key, ambiguous keys matching no entity
+func lookupEntry(entries map[string]fuzzyEntry, key string) (catalog.EntityInfo, bool) {
+	entry, ok := entries[key]
+	if !ok || entry.ambiguous {
+		return catalog.EntityInfo{}, false
+	}
+	return entry.info, true
+}
+
+// lookup tries the enabled tiers in order
+func (index *fuzzyIndex) lookup(cfg *FuzzyMatchConfig, name string) (catalog.EntityInfo, MatchType, bool) {
+	if index.folded != nil {
+		if info, ok := lookupEntry(index.folded, strings.ToLower(name)); ok {
+			return info, MatchTypeCaseInsensitive, true
+		}
+	}
+	normalized := cfg.normalize(name)
+	if cfg.NormalizeSeparators {
+		if info, ok := lookupEntry(index.normalized, normalized); ok {
+			return info, MatchTypeSeparators, true
+		}
+	}
+	if stripped := stripAffixes(cfg, normalized); stripped != normalized {
+		if info, ok := lookupEntry(index.normalized, stripped); ok {
+			return info, MatchTypeAffixes, true
+		}
+	}
+	if cfg.MaxEditDistance > 0 {
+		if info, ok := index.closest(normalized, cfg.MaxEditDistance); ok {
+			return info, MatchTypeEditDistance, true
+		}
+	}
+	return catalog.EntityInfo{}, "", false
+}
+
+// stripAffixes removes the first matching prefix and suffix from a normalized name
+func stripAffixes(cfg *FuzzyMatchConfig, name string) string {
+	for _, prefix := range cfg.StripPrefixes {
+		if stripped, ok := strings.CutPrefix(name, cfg.normalize(prefix)); ok && stripped != "" {
+			name = stripped
+			break
+		}
+	}
+	for _, suffix := range cfg.StripSuffixes {
+		if stripped, ok := strings.CutSuffix(name, cfg.normalize(suffix)); ok && stripped != "" {
+			name = stripped
+			break
+		}
+	}
+	return name
+}
+
+// closest returns the entity of the key closest to the name within the maximum distance,
+// unless keys of another entity are as close
+func (index *fuzzyIndex) closest(name string, maxDistance int) (catalog.EntityInfo, bool) {
+	best := maxDistance + 1
+	var match fuzzyEntry
+	for _, key := range index.keys {
+		distance := editDistance(name, key, best)
+		switch entry := index.normalized[key]; {
+		case distance < best:
+			best, match = distance, entry
+		case distance == best && best <= maxDistance && entry.info.EntityRef != match.info.EntityRef:
+			match.ambiguous = true
+		}
+	}
+	if best > maxDistance || match.ambiguous {
+		return catalog.EntityInfo{}, false
+	}
+	return match.info, true
+}
+
+// editDistance returns the Levenshtein distance of two strings, or any value above limit
+// once the distance is known to exceed it
+func editDistance(a string, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > limit || -diff > limit {
+		return limit + 1
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
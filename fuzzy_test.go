@@ -0,0 +1,137 @@
+package backstageprocessor
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestFuzzyMatcher(t *testing.T) {
+	checkout := catalog.EntityInfo{Org: "shop", EntityRef: "resource:default/checkout"}
+	payments := catalog.EntityInfo{Org: "payments", EntityRef: "resource:default/payments"}
+	svcA := catalog.EntityInfo{Org: "a", EntityRef: "component:default/svc-a"}
+	svcB := catalog.EntityInfo{Org: "b", EntityRef: "component:default/svc-b"}
+	snapshot := &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{
+		"acme-checkout":             checkout,
+		"acme/checkout":             checkout,
+		"resource:default/checkout": checkout,
+		"acme-payments":             payments,
+		"svc-a":                     svcA,
+		"svc-b":                     svcB,
+	}}
+	fuzzy := NewFuzzyMatcher(FuzzyMatchConfig{
+		CaseInsensitive:     true,
+		NormalizeSeparators: true,
+		StripSuffixes:       []string{"-service"},
+		MaxEditDistance:     1,
+	})
+
+	tests := []struct {
+		name      string
+		service   string
+		expected  catalog.EntityInfo
+		matchType MatchType
+	}{
+		{name: "case insensitive", service: "ACME-Checkout", expected: checkout, matchType: MatchTypeCaseInsensitive},
+		{name: "separators", service: "Acme_Checkout", expected: checkout, matchType: MatchTypeSeparators},
+		{name: "dots", service: "acme.payments", expected: payments, matchType: MatchTypeSeparators},
+		{name: "suffix", service: "acme-checkout_service", expected: checkout, matchType: MatchTypeAffixes},
+		{name: "edit distance", service: "acme-chekout", expected: checkout, matchType: MatchTypeEditDistance},
+		{name: "ambiguous edit distance", service: "svc-c"},
+		{name: "too far", service: "acme-billing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, matchType, matched := fuzzy.Lookup(snapshot, tt.service)
+			assert.Equal(t, tt.matchType != "", matched)
+			assert.Equal(t, tt.expected, info)
+			assert.Equal(t, tt.matchType, matchType)
+		})
+	}
+
+	t.Run("cache is reset with the snapshot", func(t *testing.T) {
+		_, _, matched := fuzzy.Lookup(snapshot, "Acme-Billing")
+		require.False(t, matched)
+
+		billing := catalog.EntityInfo{Org: "billing", EntityRef: "resource:default/billing"}
+		refreshed := &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{"acme-billing": billing}}
+		info, matchType, matched := fuzzy.Lookup(refreshed, "Acme-Billing")
+		require.True(t, matched)
+		assert.Equal(t, billing, info)
+		assert.Equal(t, MatchTypeCaseInsensitive, matchType)
+	})
+
+	t.Run("concurrent lookups keep the results of their snapshot", func(t *testing.T) {
+		renamed := &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{"acme-checkout": payments}}
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					current, expected := snapshot, checkout
+					if i%2 == 1 {
+						current, expected = renamed, payments
+					}
+					info, _, matched := fuzzy.Lookup(current, "acme-chekout")
+					assert.True(t, matched)
+					assert.Equal(t, expected, info)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Nil(t, NewFuzzyMatcher(FuzzyMatchConfig{CacheSize: 10}))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.EqualError(t, (&FuzzyMatchConfig{MaxEditDistance: -1}).Validate(), "fuzzy_match.max_edit_distance must not be negative")
+		assert.EqualError(t, (&FuzzyMatchConfig{CacheSize: -1}).Validate(), "fuzzy_match.cache_size must not be negative")
+	})
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("checkout", "checkout", 2))
+	assert.Equal(t, 1, editDistance("checkout", "chekout", 2))
+	assert.Equal(t, 2, editDistance("checkout", "chockoat", 2))
+	assert.Equal(t, 3, editDistance("checkout", "payments", 2), "distances above the limit are capped")
+	assert.Equal(t, 3, editDistance("api", "checkout-api", 2))
+}
+
+func TestProcessAttrsMatchType(t *testing.T) {
+	checkout := catalog.EntityInfo{Org: "shop", Division: "retail", EntityRef: "resource:default/checkout"}
+	cfg := Config{FuzzyMatch: FuzzyMatchConfig{NormalizeSeparators: true}}
+	processor := &backstageprocessor{
+		logger:  zap.NewNop(),
+		config:  cfg,
+		catalog: &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{"acme-checkout": checkout}},
+		fuzzy:   NewFuzzyMatcher(cfg.FuzzyMatch),
+	}
+
+	for service, matchType := range map[string]MatchType{"acme-checkout": MatchTypeExact, "ACME_checkout": MatchTypeSeparators} {
+		attributes := pcommon.NewMap()
+		attributes.PutStr(serviceNameKey, service)
+		info, matched, _ := processor.processAttrs(context.Background(), attributes)
+		require.True(t, matched, service)
+		assert.Equal(t, checkout, info)
+		v, found := attributes.Get(matchTypeKey)
+		require.True(t, found, service)
+		assert.Equal(t, string(matchType), v.Str())
+	}
+
+	attributes := pcommon.NewMap()
+	attributes.PutStr(serviceNameKey, "billing")
+	_, matched, _ := processor.processAttrs(context.Background(), attributes)
+	require.False(t, matched)
+	_, found := attributes.Get(matchTypeKey)
+	assert.False(t, found, "unmatched services have no match type")
+}
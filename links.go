@@ -0,0 +1,106 @@
+package backstageprocessor
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// defaultLinkPrefix is the prefix of the attributes holding the entity links by default.
+const defaultLinkPrefix = "backstage.link."
+
+// entityURLKey is the attribute holding the URL of the entity page in the Backstage frontend
+const entityURLKey = "backstage.entity.url"
+
+// LinksConfig adds the page of the matched entity in the Backstage frontend, and selected
+// entries of its metadata.links, as attributes. Unmatched services get no link attribute.
+type LinksConfig struct {
+	// FrontendURL is the base URL of the Backstage frontend, the backstage.entity.url attribute
+	// linking to the catalog page of the entity. The attribute isn't set when empty.
+	FrontendURL string `mapstructure:"frontend_url"`
+
+	// Types lists the types of the metadata.links entries added as attributes, such as runbook
+	// or dashboard. The attribute is named after the type.
+	Types []string `mapstructure:"types"`
+
+	// Titles lists the titles of the metadata.links entries added as attributes, compared
+	// case-insensitively. The attribute is named after the title, in lower case with _ in
+	// place of spaces.
+	Titles []string `mapstructure:"titles"`
+
+	// Prefix is the prefix of the link attributes. Defaults to backstage.link.
+	Prefix string `mapstructure:"prefix"`
+}
+
+// Validate checks if the links configuration is valid.
+func (cfg *LinksConfig) Validate() error {
+	if cfg.FrontendURL == "" {
+		return nil
+	}
+	u, err := url.Parse(cfg.FrontendURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("links.frontend_url %q must be an absolute URL", cfg.FrontendURL)
+	}
+	return nil
+}
+
+// enabled reports whether any link attribute is added.
+func (cfg *LinksConfig) enabled() bool {
+	return cfg.FrontendURL != "" || len(cfg.Types) > 0 || len(cfg.Titles) > 0
+}
+
+// prefix returns the configured attribute prefix, falling back to backstage.link.
+func (cfg *LinksConfig) prefix() string {
+	if cfg.Prefix == "" {
+		return defaultLinkPrefix
+	}
+	return cfg.Prefix
+}
+
+// entityURL returns the catalog page of an entity, empty for entities without name
+func (cfg *LinksConfig) entityURL(info catalog.EntityInfo) string {
+	if cfg.FrontendURL == "" || info.Kind == "" || info.Name == "" {
+		return ""
+	}
+	namespace := info.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	return strings.TrimSuffix(cfg.FrontendURL, "/") + "/catalog/" + url.PathEscape(namespace) + "/" +
+		url.PathEscape(strings.ToLower(info.Kind)) + "/" + url.PathEscape(info.Name)
+}
+
+// linkKey returns the attribute key of a link selected by its type or title, false when the
+// link isn't selected
+func (cfg *LinksConfig) linkKey(link catalog.EntityLink) (string, bool) {
+	if link.Type != "" && slices.Contains(cfg.Types, link.Type) {
+		return cfg.prefix() + link.Type, true
+	}
+	for _, title := range cfg.Titles {
+		if strings.EqualFold(title, link.Title) {
+			return cfg.prefix() + strings.ReplaceAll(strings.ToLower(strings.TrimSpace(link.Title)), " ", "_"), true
+		}
+	}
+	return "", false
+}
+
+// apply adds the link attributes of a matched entity. The first link of each key wins.
+func (cfg *LinksConfig) apply(attributes pcommon.Map, info catalog.EntityInfo) {
+	if u := cfg.entityURL(info); u != "" {
+		attributes.PutStr(entityURLKey, u)
+	}
+	var added []string
+	for _, link := range info.Links {
+		key, ok := cfg.linkKey(link)
+		if !ok || slices.Contains(added, key) {
+			continue
+		}
+		attributes.PutStr(key, link.URL)
+		added = append(added, key)
+	}
+}
@@ -0,0 +1,113 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestProcessAttrsLinks(t *testing.T) {
+	checkout := catalog.EntityInfo{
+		Org: "shop", Kind: "Component", Namespace: "default", Name: "checkout", EntityRef: "component:default/checkout",
+		Links: []catalog.EntityLink{
+			{URL: "https://runbooks.example.com/checkout", Title: "Runbook", Type: "runbook"},
+			{URL: "https://runbooks.example.com/checkout-old", Title: "Old runbook", Type: "runbook"},
+			{URL: "https://grafana.example.com/d/checkout", Title: "Grafana Dashboard"},
+			{URL: "https://github.com/acme/checkout", Title: "Repository", Type: "source"},
+		},
+	}
+	processor := &backstageprocessor{
+		logger: zap.NewNop(),
+		config: Config{Links: LinksConfig{
+			FrontendURL: "https://backstage.example.com/",
+			Types:       []string{"runbook"},
+			Titles:      []string{"grafana dashboard"},
+		}},
+		catalog: &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{"checkout": checkout}},
+	}
+
+	attributes := pcommon.NewMap()
+	attributes.PutStr(serviceNameKey, "checkout")
+	_, matched, _ := processor.processAttrs(context.Background(), attributes)
+	require.True(t, matched)
+	assert.Equal(t, map[string]any{
+		serviceNameKey:                     "checkout",
+		orgKey:                             "shop",
+		divisionKey:                        "",
+		refKey:                             "component:default/checkout",
+		entityURLKey:                       "https://backstage.example.com/catalog/default/component/checkout",
+		"backstage.link.runbook":           "https://runbooks.example.com/checkout",
+		"backstage.link.grafana_dashboard": "https://grafana.example.com/d/checkout",
+	}, attributes.AsRaw())
+
+	unmatched := pcommon.NewMap()
+	unmatched.PutStr(serviceNameKey, "billing")
+	_, matched, _ = processor.processAttrs(context.Background(), unmatched)
+	require.False(t, matched)
+	assert.Equal(t, map[string]any{serviceNameKey: "billing", orgKey: unknown, divisionKey: unknown}, unmatched.AsRaw())
+}
+
+func TestProcessMetricsLinks(t *testing.T) {
+	checkout := catalog.EntityInfo{
+		Org: "shop", Kind: "Component", Namespace: "default", Name: "checkout", EntityRef: "component:default/checkout",
+		Links: []catalog.EntityLink{{URL: "https://runbooks.example.com/checkout", Type: "runbook"}},
+	}
+	cfg := Config{
+		Metrics: MetricsConfig{Exemplars: true},
+		Links:   LinksConfig{FrontendURL: "https://backstage.example.com", Types: []string{"runbook"}},
+	}
+	selector, err := cfg.Metrics.selector()
+	require.NoError(t, err)
+	processor := &backstageprocessor{
+		logger:  zap.NewNop(),
+		config:  cfg,
+		catalog: &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{"checkout": checkout}},
+		metrics: selector,
+	}
+
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr(serviceNameKey, "checkout")
+	dp := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptySum().DataPoints().AppendEmpty()
+	dp.Attributes().PutStr(serviceNameKey, "checkout")
+	exemplar := dp.Exemplars().AppendEmpty()
+	exemplar.FilteredAttributes().PutStr(serviceNameKey, "checkout")
+
+	_, err = processor.processMetrics(context.Background(), md)
+	require.NoError(t, err)
+
+	_, found := rm.Resource().Attributes().Get(entityURLKey)
+	assert.True(t, found, "the resource gets the links")
+	for name, attributes := range map[string]pcommon.Map{"data point": dp.Attributes(), "exemplar": exemplar.FilteredAttributes()} {
+		_, found = attributes.Get(refKey)
+		assert.True(t, found, name)
+		_, found = attributes.Get(entityURLKey)
+		assert.False(t, found, "the %s has no link attribute", name)
+		_, found = attributes.Get("backstage.link.runbook")
+		assert.False(t, found, "the %s has no link attribute", name)
+	}
+}
+
+func TestLinksConfig(t *testing.T) {
+	assert.NoError(t, (&LinksConfig{}).Validate())
+	assert.NoError(t, (&LinksConfig{FrontendURL: "https://backstage.example.com"}).Validate())
+	assert.EqualError(t, (&LinksConfig{FrontendURL: "backstage.example.com"}).Validate(), `links.frontend_url "backstage.example.com" must be an absolute URL`)
+
+	cfg := LinksConfig{FrontendURL: "https://backstage.example.com/portal", Prefix: "links."}
+	assert.Equal(t, "https://backstage.example.com/portal/catalog/team-a/api/orders", cfg.entityURL(catalog.EntityInfo{Kind: "API", Namespace: "team-a", Name: "orders"}))
+	assert.Empty(t, cfg.entityURL(catalog.EntityInfo{}))
+
+	cfg.Types = []string{"dashboard"}
+	key, ok := cfg.linkKey(catalog.EntityLink{URL: "https://grafana.example.com", Type: "dashboard"})
+	assert.True(t, ok)
+	assert.Equal(t, "links.dashboard", key)
+	_, ok = cfg.linkKey(catalog.EntityLink{URL: "https://github.com", Type: "source"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,182 @@
+package backstageprocessor
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// resource attribute keys set by the k8sattributes processor
+const (
+	k8sDeploymentNameKey = "k8s.deployment.name"
+	k8sNamespaceNameKey  = "k8s.namespace.name"
+)
+
+// resource attribute keys of the VCS semantic conventions
+const (
+	vcsRepositoryURLKey  = "vcs.repository.url.full"
+	vcsRepositoryNameKey = "vcs.repository.name"
+	vcsOwnerNameKey      = "vcs.owner.name"
+)
+
+// Matcher matches attributes against the catalog entities with the match strategies
+// of the processor. It is shared by the components of this module looking up the catalog.
+type Matcher struct {
+	// Strategies are tried in order until one of them matches. Defaults to service_name.
+	Strategies []MatchStrategy
+	// Kubernetes configures the kubernetes match strategy.
+	Kubernetes KubernetesMatchConfig
+	// Fuzzy looks up the service names missing the exact lookup of the service_name strategy
+	// with the fuzzy match tiers. Service names are only matched as is when nil.
+	Fuzzy *FuzzyMatcher
+}
+
+// Validate checks that every match strategy is known.
+func (m Matcher) Validate() error {
+	for _, strategy := range m.Strategies {
+		switch strategy {
+		case MatchServiceName, MatchKubernetes, MatchVCSRepository:
+		default:
+			return fmt.Errorf("unknown match strategy %q", strategy)
+		}
+	}
+	return nil
+}
+
+// strategies returns the match strategies, falling back to service_name.
+func (m Matcher) strategies() []MatchStrategy {
+	if len(m.Strategies) == 0 {
+		return []MatchStrategy{MatchServiceName}
+	}
+	return m.Strategies
+}
+
+// Match runs the match strategies in order against the snapshot. identified reports whether
+// any of the attributes used by the strategies was found, even if it didn't match an entity.
+func (m Matcher) Match(snapshot *catalog.Snapshot, attributes pcommon.Map) (info catalog.EntityInfo, identified bool, matched bool) {
+	info, _, identified, matched = m.MatchWithType(snapshot, attributes)
+	return info, identified, matched
+}
+
+// MatchWithType runs the match strategies as Match does, and also returns the tier that
+// matched the entity, exact unless the service_name strategy matched with the fuzzy tiers.
+func (m Matcher) MatchWithType(snapshot *catalog.Snapshot, attributes pcommon.Map) (info catalog.EntityInfo, matchType MatchType, identified bool, matched bool) {
+	for _, strategy := range m.strategies() {
+		var found, ok bool
+		matchType = MatchTypeExact
+		switch strategy {
+		case MatchServiceName:
+			info, matchType, found, ok = m.matchServiceName(snapshot, attributes)
+		case MatchKubernetes:
+			info, found, ok = m.matchKubernetes(snapshot, attributes)
+		case MatchVCSRepository:
+			info, found, ok = matchVCSRepository(snapshot, attributes)
+		}
+		identified = identified || found
+		if ok {
+			return info, matchType, true, true
+		}
+	}
+	return catalog.EntityInfo{}, "", identified, false
+}
+
+// match runs the configured match strategies in order
+func (b *backstageprocessor) match(attributes pcommon.Map) (info catalog.EntityInfo, identified bool, matched bool) {
+	info, _, identified, matched = b.matchWithType(attributes)
+	return info, identified, matched
+}
+
+// matchWithType runs the configured match strategies in order, returning the tier that matched
+func (b *backstageprocessor) matchWithType(attributes pcommon.Map) (info catalog.EntityInfo, matchType MatchType, identified bool, matched bool) {
+	matcher := b.config.matcher()
+	matcher.Fuzzy = b.fuzzy
+	// the same snapshot is used by every strategy, even if a refresh happens meanwhile
+	return matcher.MatchWithType(b.catalog.Snapshot(), attributes)
+}
+
+// matchServiceName looks up the service.name attribute, then falls back to the fuzzy tiers
+func (m Matcher) matchServiceName(snapshot *catalog.Snapshot, attributes pcommon.Map) (catalog.EntityInfo, MatchType, bool, bool) {
+	repo, found := attributes.Get(serviceNameKey)
+	if !found {
+		return catalog.EntityInfo{}, "", false, false
+	}
+	if info, ok := snapshot.Lookup(repo.Str()); ok {
+		return info, MatchTypeExact, true, true
+	}
+	if m.Fuzzy == nil {
+		return catalog.EntityInfo{}, "", true, false
+	}
+	info, matchType, ok := m.Fuzzy.Lookup(snapshot, repo.Str())
+	return info, matchType, true, ok
+}
+
+// matchVCSRepository looks up the repository of the vcs.repository.url.full attribute,
+// falling back to the vcs.owner.name and vcs.repository.name attributes
+func matchVCSRepository(snapshot *catalog.Snapshot, attributes pcommon.Map) (catalog.EntityInfo, bool, bool) {
+	if u, found := attributes.Get(vcsRepositoryURLKey); found {
+		if repository, ok := catalog.NormalizeRepository(u.Str()); ok {
+			info, ok := snapshot.Lookup(repository)
+			return info, true, ok
+		}
+	}
+
+	owner, hasOwner := attributes.Get(vcsOwnerNameKey)
+	name, hasName := attributes.Get(vcsRepositoryNameKey)
+	if !hasOwner || !hasName {
+		return catalog.EntityInfo{}, hasName, false
+	}
+	info, ok := snapshot.Lookup(owner.Str() + "/" + name.Str())
+	return info, true, ok
+}
+
+// matchKubernetes matches the pod labels and the deployment name against the
+// backstage.io/kubernetes-id annotation, then the pod labels against the
+// backstage.io/kubernetes-label-selector annotations. Entities with the
+// backstage.io/kubernetes-namespace annotation only match in that namespace.
+func (m Matcher) matchKubernetes(snapshot *catalog.Snapshot, attributes pcommon.Map) (catalog.EntityInfo, bool, bool) {
+	prefix := m.Kubernetes.podLabelPrefix()
+	podLabel := func(key string) (string, bool) {
+		v, ok := attributes.Get(prefix + key)
+		if !ok {
+			return "", false
+		}
+		return v.AsString(), true
+	}
+
+	deployment, hasDeployment := attributes.Get(k8sDeploymentNameKey)
+	namespace, hasNamespace := attributes.Get(k8sNamespaceNameKey)
+	hasPodLabels := false
+	attributes.Range(func(k string, _ pcommon.Value) bool {
+		hasPodLabels = strings.HasPrefix(k, prefix)
+		return !hasPodLabels
+	})
+	if !hasDeployment && !hasPodLabels {
+		return catalog.EntityInfo{}, false, false
+	}
+
+	inNamespace := func(info catalog.EntityInfo) bool {
+		return info.KubernetesNamespace == "" || (hasNamespace && namespace.Str() == info.KubernetesNamespace)
+	}
+
+	if id, ok := podLabel(catalog.KubernetesIDAnnotation); ok {
+		if info, ok := snapshot.KubernetesIDs[id]; ok && inNamespace(info) {
+			return info, true, true
+		}
+	}
+	if hasDeployment {
+		if info, ok := snapshot.KubernetesIDs[deployment.Str()]; ok && inNamespace(info) {
+			return info, true, true
+		}
+	}
+	if hasPodLabels {
+		for _, s := range snapshot.Selectors {
+			if inNamespace(s.Entity) && s.Selector.Matches(podLabel) {
+				return s.Entity, true, true
+			}
+		}
+	}
+	return catalog.EntityInfo{}, true, false
+}
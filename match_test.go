@@ -0,0 +1,164 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestMatchKubernetes(t *testing.T) {
+	checkout := RepoInfo{Org: "checkout-org", Division: "shop", EntityRef: "component:default/checkout", KubernetesID: "checkout"}
+	payments := RepoInfo{Org: "payments-org", Division: "shop", EntityRef: "component:default/payments", KubernetesID: "payments", KubernetesNamespace: "payments"}
+	search := RepoInfo{Org: "search-org", Division: "discovery", EntityRef: "component:default/search", KubernetesLabelSelector: "app=search,tier in (api,web)"}
+	selector, err := catalog.ParseLabelSelector(search.KubernetesLabelSelector)
+	require.NoError(t, err)
+
+	processor := &backstageprocessor{
+		logger: zap.NewNop(),
+		config: Config{MatchStrategies: []MatchStrategy{MatchServiceName, MatchKubernetes}},
+		catalog: &catalog.Snapshot{
+			Keys: map[string]RepoInfo{
+				"checkout": checkout,
+			},
+			KubernetesIDs: map[string]RepoInfo{
+				"checkout": checkout,
+				"payments": payments,
+			},
+			Selectors: []catalog.KubernetesSelector{{Selector: selector, Entity: search}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[string]any
+		wantOrg    string
+	}{
+		{
+			name:       "service name first",
+			attributes: map[string]any{serviceNameKey: "checkout", k8sDeploymentNameKey: "payments", k8sNamespaceNameKey: "payments"},
+			wantOrg:    "checkout-org",
+		},
+		{
+			name:       "kubernetes-id pod label",
+			attributes: map[string]any{serviceNameKey: "unknown-service", "k8s.pod.labels.backstage.io/kubernetes-id": "checkout"},
+			wantOrg:    "checkout-org",
+		},
+		{
+			name:       "deployment name",
+			attributes: map[string]any{k8sDeploymentNameKey: "checkout", k8sNamespaceNameKey: "shop"},
+			wantOrg:    "checkout-org",
+		},
+		{
+			name:       "deployment name in the annotated namespace",
+			attributes: map[string]any{k8sDeploymentNameKey: "payments", k8sNamespaceNameKey: "payments"},
+			wantOrg:    "payments-org",
+		},
+		{
+			name:       "deployment name outside the annotated namespace",
+			attributes: map[string]any{k8sDeploymentNameKey: "payments", k8sNamespaceNameKey: "default"},
+			wantOrg:    unknown,
+		},
+		{
+			name:       "label selector",
+			attributes: map[string]any{"k8s.pod.labels.app": "search", "k8s.pod.labels.tier": "api"},
+			wantOrg:    "search-org",
+		},
+		{
+			name:       "label selector not matching",
+			attributes: map[string]any{"k8s.pod.labels.app": "search", "k8s.pod.labels.tier": "batch"},
+			wantOrg:    unknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pcommon.NewMap()
+			require.NoError(t, attrs.FromRaw(tt.attributes))
+
+			processor.processAttrs(context.Background(), attrs)
+
+			org, found := attrs.Get(orgKey)
+			require.True(t, found)
+			assert.Equal(t, tt.wantOrg, org.Str())
+		})
+	}
+
+	t.Run("span attributes without kubernetes attributes are left alone", func(t *testing.T) {
+		attrs := pcommon.NewMap()
+		attrs.PutStr("http.route", "/cart")
+
+		processor.processAttrs(context.Background(), attrs)
+
+		_, found := attrs.Get(orgKey)
+		assert.False(t, found)
+	})
+
+	t.Run("kubernetes attributes are ignored without the strategy", func(t *testing.T) {
+		p := &backstageprocessor{logger: zap.NewNop(), catalog: processor.catalog}
+		attrs := pcommon.NewMap()
+		attrs.PutStr(k8sDeploymentNameKey, "checkout")
+
+		p.processAttrs(context.Background(), attrs)
+
+		_, found := attrs.Get(orgKey)
+		assert.False(t, found)
+	})
+}
+
+func TestMatchVCSRepository(t *testing.T) {
+	checkout := RepoInfo{Org: "shop", EntityRef: "component:default/checkout", Repository: "acme/checkout"}
+	processor := &backstageprocessor{
+		logger: zap.NewNop(),
+		config: Config{MatchStrategies: []MatchStrategy{MatchVCSRepository, MatchServiceName}},
+		catalog: &catalog.Snapshot{Keys: map[string]RepoInfo{
+			"acme/checkout": checkout,
+			"acme-checkout": checkout,
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[string]any
+		wantOrg    string
+	}{
+		{
+			name:       "repository url",
+			attributes: map[string]any{vcsRepositoryURLKey: "https://github.com/acme/checkout", serviceNameKey: "checkout-service"},
+			wantOrg:    "shop",
+		},
+		{
+			name:       "owner and repository name",
+			attributes: map[string]any{vcsOwnerNameKey: "acme", vcsRepositoryNameKey: "checkout"},
+			wantOrg:    "shop",
+		},
+		{
+			name:       "falls back to the service name",
+			attributes: map[string]any{vcsRepositoryURLKey: "https://github.com/acme/other", serviceNameKey: "acme-checkout"},
+			wantOrg:    "shop",
+		},
+		{
+			name:       "unknown repository",
+			attributes: map[string]any{vcsRepositoryURLKey: "https://github.com/acme/other"},
+			wantOrg:    unknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pcommon.NewMap()
+			require.NoError(t, attrs.FromRaw(tt.attributes))
+
+			processor.processAttrs(context.Background(), attrs)
+
+			org, found := attrs.Get(orgKey)
+			require.True(t, found)
+			assert.Equal(t, tt.wantOrg, org.Str())
+		})
+	}
+}
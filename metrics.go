@@ -0,0 +1,120 @@
+package backstageprocessor
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// MetricsConfig defines which metric data points are enriched. The resource of the metrics is
+// always enriched.
+type MetricsConfig struct {
+	// DataPoints enriches the attributes of the data points. Defaults to true.
+	DataPoints *bool `mapstructure:"data_points"`
+
+	// Types lists the metric types whose data points are enriched: gauge, sum, histogram,
+	// exponential_histogram and summary. Defaults to every type.
+	Types []string `mapstructure:"types"`
+
+	// Include lists regular expressions matching the names of the metrics whose data points are
+	// enriched. Defaults to every metric.
+	Include []string `mapstructure:"include"`
+
+	// Exclude lists regular expressions matching the names of the metrics whose data points are
+	// not enriched, even when included.
+	Exclude []string `mapstructure:"exclude"`
+
+	// Exemplars enriches the filtered attributes of the exemplars of the enriched data points.
+	Exemplars bool `mapstructure:"exemplars"`
+}
+
+// metricTypes maps the configuration names of the metric types to their type
+var metricTypes = map[string]pmetric.MetricType{
+	"gauge":                 pmetric.MetricTypeGauge,
+	"sum":                   pmetric.MetricTypeSum,
+	"histogram":             pmetric.MetricTypeHistogram,
+	"exponential_histogram": pmetric.MetricTypeExponentialHistogram,
+	"summary":               pmetric.MetricTypeSummary,
+}
+
+// Validate checks if the metrics configuration is valid.
+func (cfg *MetricsConfig) Validate() error {
+	_, err := cfg.selector()
+	return err
+}
+
+// metricSelector selects the metrics whose data points are enriched
+type metricSelector struct {
+	disabled bool
+	types    map[pmetric.MetricType]bool
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+}
+
+// selector compiles the metric selection of the configuration. It returns nil when every
+// data point is enriched.
+func (cfg *MetricsConfig) selector() (*metricSelector, error) {
+	if cfg.DataPoints == nil && len(cfg.Types) == 0 && len(cfg.Include) == 0 && len(cfg.Exclude) == 0 {
+		return nil, nil
+	}
+
+	s := &metricSelector{disabled: cfg.DataPoints != nil && !*cfg.DataPoints}
+	if len(cfg.Types) > 0 {
+		s.types = map[pmetric.MetricType]bool{}
+	}
+	for _, name := range cfg.Types {
+		metricType, ok := metricTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown metric type %q", name)
+		}
+		s.types[metricType] = true
+	}
+
+	var err error
+	if s.include, err = compilePatterns("include", cfg.Include); err != nil {
+		return nil, err
+	}
+	if s.exclude, err = compilePatterns("exclude", cfg.Exclude); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compilePatterns compiles the regular expressions of a metric name list
+func compilePatterns(name string, patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics.%s pattern %q: %w", name, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// selected reports whether the data points of a metric are enriched. A nil selector selects
+// every metric.
+func (s *metricSelector) selected(metric pmetric.Metric) bool {
+	if s == nil {
+		return true
+	}
+	if s.disabled || (s.types != nil && !s.types[metric.Type()]) {
+		return false
+	}
+	if len(s.include) > 0 && !matchesAny(s.include, metric.Name()) {
+		return false
+	}
+	return !matchesAny(s.exclude, metric.Name())
+}
+
+// matchesAny reports whether any of the regular expressions matches the name
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestMetricSelector(t *testing.T) {
+	disabled := false
+	newMetric := func(name string, metricType pmetric.MetricType) pmetric.Metric {
+		metric := pmetric.NewMetric()
+		metric.SetName(name)
+		switch metricType {
+		case pmetric.MetricTypeSum:
+			metric.SetEmptySum()
+		case pmetric.MetricTypeHistogram:
+			metric.SetEmptyHistogram()
+		}
+		return metric
+	}
+
+	tests := []struct {
+		name     string
+		config   MetricsConfig
+		metric   pmetric.Metric
+		expected bool
+	}{
+		{name: "default", metric: newMetric("http.server.duration", pmetric.MetricTypeHistogram), expected: true},
+		{name: "data points disabled", config: MetricsConfig{DataPoints: &disabled}, metric: newMetric("calls", pmetric.MetricTypeSum)},
+		{name: "selected type", config: MetricsConfig{Types: []string{"sum"}}, metric: newMetric("calls", pmetric.MetricTypeSum), expected: true},
+		{name: "other type", config: MetricsConfig{Types: []string{"sum"}}, metric: newMetric("latency", pmetric.MetricTypeHistogram)},
+		{name: "included", config: MetricsConfig{Include: []string{"^service\\."}}, metric: newMetric("service.calls", pmetric.MetricTypeSum), expected: true},
+		{name: "not included", config: MetricsConfig{Include: []string{"^service\\."}}, metric: newMetric("http.calls", pmetric.MetricTypeSum)},
+		{name: "excluded", config: MetricsConfig{Include: []string{"^service\\."}, Exclude: []string{"duration$"}}, metric: newMetric("service.duration", pmetric.MetricTypeHistogram)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := tt.config.selector()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, selector.selected(tt.metric))
+		})
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		assert.EqualError(t, (&MetricsConfig{Types: []string{"counter"}}).Validate(), `unknown metric type "counter"`)
+		assert.ErrorContains(t, (&MetricsConfig{Exclude: []string{"("}}).Validate(), `invalid metrics.exclude pattern "("`)
+	})
+}
+
+func TestProcessMetricsSelection(t *testing.T) {
+	cfg := Config{Metrics: MetricsConfig{Types: []string{"sum"}, Exemplars: true}}
+	selector, err := cfg.Metrics.selector()
+	require.NoError(t, err)
+	processor := &backstageprocessor{
+		logger:  zap.NewNop(),
+		config:  cfg,
+		catalog: &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{"checkout": {Org: "shop", Division: "retail"}}},
+		metrics: selector,
+	}
+
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr(serviceNameKey, "checkout")
+	metrics := rm.ScopeMetrics().AppendEmpty().Metrics()
+	sum := metrics.AppendEmpty()
+	sum.SetName("calls")
+	dp := sum.SetEmptySum().DataPoints().AppendEmpty()
+	dp.Attributes().PutStr(serviceNameKey, "checkout")
+	dp.Exemplars().AppendEmpty().FilteredAttributes().PutStr(serviceNameKey, "checkout")
+	dp.Exemplars().AppendEmpty().FilteredAttributes().PutStr("http.route", "/cart")
+	histogram := metrics.AppendEmpty()
+	histogram.SetName("latency")
+	hdp := histogram.SetEmptyHistogram().DataPoints().AppendEmpty()
+	hdp.Attributes().PutStr(serviceNameKey, "checkout")
+
+	_, err = processor.processMetrics(context.Background(), md)
+	require.NoError(t, err)
+
+	org, _ := rm.Resource().Attributes().Get(orgKey)
+	assert.Equal(t, "shop", org.Str(), "the resource is always enriched")
+	org, _ = dp.Attributes().Get(orgKey)
+	assert.Equal(t, "shop", org.Str())
+	org, _ = dp.Exemplars().At(0).FilteredAttributes().Get(orgKey)
+	assert.Equal(t, "shop", org.Str())
+	assert.Equal(t, map[string]any{"http.route": "/cart"}, dp.Exemplars().At(1).FilteredAttributes().AsRaw())
+	assert.Equal(t, map[string]any{serviceNameKey: "checkout"}, hdp.Attributes().AsRaw(), "histograms are not selected")
+}
@@ -0,0 +1,100 @@
+package backstageprocessor
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// defaultPeerPrefix is the prefix of the attributes describing the peer entity by default.
+const defaultPeerPrefix = "backstage.peer."
+
+// span attribute keys identifying the peer of a client span
+const (
+	serverAddressKey = "server.address"
+	dbSystemKey      = "db.system"
+	dbNameKey        = "db.name"
+)
+
+// peerKinds are the kinds of the entities a peer name is looked up as, after the lookup keys
+var peerKinds = []string{"component", "api", "resource"}
+
+// PeerConfig enriches client spans with the entity of the downstream dependency they call,
+// so that every edge of a service graph can be attributed to the team owning the dependency.
+type PeerConfig struct {
+	// Enabled looks up the peer.service, server.address or db.system and db.name attributes
+	// of client and producer spans.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Prefix is the prefix of the attributes describing the peer entity. Defaults to backstage.peer.
+	Prefix string `mapstructure:"prefix"`
+}
+
+// prefix returns the configured attribute prefix, falling back to backstage.peer.
+func (cfg *PeerConfig) prefix() string {
+	if cfg.Prefix == "" {
+		return defaultPeerPrefix
+	}
+	return cfg.Prefix
+}
+
+// processPeer adds the attributes of the peer entity to client and producer spans
+func (b *backstageprocessor) processPeer(span ptrace.Span) {
+	if kind := span.Kind(); kind != ptrace.SpanKindClient && kind != ptrace.SpanKindProducer {
+		return
+	}
+	info, ok := lookupPeer(b.catalog.Snapshot(), span.Attributes())
+	if !ok {
+		return
+	}
+
+	prefix := b.config.Peer.prefix()
+	attributes := span.Attributes()
+	for _, attr := range []struct{ key, value string }{
+		{"owner", info.Owner},
+		{"system", info.System},
+		{"org", info.Org},
+		{"division", info.Division},
+		{"entity.ref", info.EntityRef},
+	} {
+		if attr.value != "" {
+			attributes.PutStr(prefix+attr.key, attr.value)
+		}
+	}
+}
+
+// lookupPeer looks up the peer.service attribute, then the server.address one, then the
+// database of the span as <db.system>-<db.name>. Each name is looked up as a key, then as the
+// name of a component, API or resource entity of the default namespace. Generic names, such
+// as the first label of a host name or a bare database name, aren't looked up: they would
+// attribute third-party or shared dependencies to an unrelated entity of the same name.
+func lookupPeer(snapshot *catalog.Snapshot, attributes pcommon.Map) (catalog.EntityInfo, bool) {
+	var names []string
+	if peer, ok := attributes.Get(peerServiceKey); ok {
+		names = append(names, peer.Str())
+	}
+	if address, ok := attributes.Get(serverAddressKey); ok {
+		names = append(names, address.Str())
+	}
+	db, hasDB := attributes.Get(dbNameKey)
+	system, hasSystem := attributes.Get(dbSystemKey)
+	if hasDB && hasSystem && db.Str() != "" && system.Str() != "" {
+		names = append(names, system.Str()+"-"+db.Str())
+	}
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if info, ok := snapshot.Lookup(name); ok {
+			return info, true
+		}
+		for _, kind := range peerKinds {
+			if info, ok := snapshot.Lookup(kind + ":" + name); ok {
+				return info, true
+			}
+		}
+	}
+	return catalog.EntityInfo{}, false
+}
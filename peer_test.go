@@ -0,0 +1,99 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestLookupPeer(t *testing.T) {
+	snapshot := &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{
+		"payments-gateway":          {EntityRef: "component:default/payments-gateway"},
+		"api:default/ledger":        {EntityRef: "api:default/ledger"},
+		"resource:default/orders":   {EntityRef: "resource:default/orders"},
+		"resource:default/10":       {EntityRef: "resource:default/10"},
+		"resource:default/redis-cs": {EntityRef: "resource:default/redis-cs"},
+		"postgresql-invoices":       {EntityRef: "resource:default/invoices-db"},
+	}}
+
+	tests := []struct {
+		name       string
+		attributes map[string]any
+		expected   string
+	}{
+		{name: "peer service", attributes: map[string]any{"peer.service": "payments-gateway"}, expected: "component:default/payments-gateway"},
+		{name: "api entity", attributes: map[string]any{"peer.service": "ledger"}, expected: "api:default/ledger"},
+		{name: "server address", attributes: map[string]any{"server.address": "ledger"}, expected: "api:default/ledger"},
+		{name: "first label of a host name", attributes: map[string]any{"server.address": "ledger.internal.example.com"}},
+		{name: "IP address", attributes: map[string]any{"server.address": "10.0.0.1"}},
+		{name: "database key", attributes: map[string]any{"db.system": "postgresql", "db.name": "invoices"}, expected: "resource:default/invoices-db"},
+		{name: "bare database name", attributes: map[string]any{"db.system": "postgresql", "db.name": "orders"}},
+		{name: "peer service first", attributes: map[string]any{"peer.service": "payments-gateway", "db.system": "postgresql", "db.name": "invoices"}, expected: "component:default/payments-gateway"},
+		{name: "falls back to the next attribute", attributes: map[string]any{"peer.service": "unknown", "server.address": "redis-cs"}, expected: "resource:default/redis-cs"},
+		{name: "unknown peer", attributes: map[string]any{"peer.service": "unknown"}},
+		{name: "no peer attributes", attributes: map[string]any{"http.method": "GET"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := ptrace.NewSpan()
+			require.NoError(t, span.Attributes().FromRaw(tt.attributes))
+			info, ok := lookupPeer(snapshot, span.Attributes())
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, info.EntityRef)
+		})
+	}
+}
+
+func TestProcessPeer(t *testing.T) {
+	processor := &backstageprocessor{
+		logger: zap.NewNop(),
+		config: Config{Peer: PeerConfig{Enabled: true}},
+		catalog: &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{
+			"checkout": {Org: "shop", EntityRef: "component:default/checkout"},
+			"api:default/payments": {
+				Org: "finance", EntityRef: "api:default/payments", Owner: "group:default/payments-team", System: "billing",
+			},
+		}},
+	}
+
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr(serviceNameKey, "checkout")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	client := spans.AppendEmpty()
+	client.SetKind(ptrace.SpanKindClient)
+	client.Attributes().PutStr(peerServiceKey, "payments")
+	server := spans.AppendEmpty()
+	server.SetKind(ptrace.SpanKindServer)
+	server.Attributes().PutStr(peerServiceKey, "payments")
+
+	_, err := processor.processTraces(context.Background(), traces)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		peerServiceKey:              "payments",
+		"backstage.peer.owner":      "group:default/payments-team",
+		"backstage.peer.system":     "billing",
+		"backstage.peer.org":        "finance",
+		"backstage.peer.entity.ref": "api:default/payments",
+	}, client.Attributes().AsRaw())
+	assert.Equal(t, map[string]any{peerServiceKey: "payments"}, server.Attributes().AsRaw(), "only client and producer spans are enriched")
+	org, _ := rs.Resource().Attributes().Get(orgKey)
+	assert.Equal(t, "shop", org.Str(), "the emitting service is still enriched")
+
+	t.Run("custom prefix", func(t *testing.T) {
+		processor.config.Peer.Prefix = "dependency."
+		span := ptrace.NewSpan()
+		span.SetKind(ptrace.SpanKindProducer)
+		span.Attributes().PutStr(peerServiceKey, "payments")
+		processor.processPeer(span)
+		owner, _ := span.Attributes().Get("dependency.owner")
+		assert.Equal(t, "group:default/payments-team", owner.Str())
+	})
+}
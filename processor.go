@@ -2,247 +2,507 @@ package backstageprocessor
 
 import (
 	"context"
-	"sync"
-	"time"
+	"errors"
+	"fmt"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
 )
 
-const serviceNameKey = "service.name"
+const (
+	serviceNameKey = "service.name"
+	peerServiceKey = "peer.service"
+)
 
 // attribute keys
 const (
 	orgKey      = "backstage.org"
 	divisionKey = "backstage.division"
+	sourceKey   = "backstage.source"
+	refKey      = "backstage.entity.ref"
 	unknown     = "unknown"
 )
 
+// RepoInfo is the enrichment data of a catalog entity.
+//
+// Deprecated: use catalog.EntityInfo instead.
+type RepoInfo = catalog.EntityInfo
+
 type backstageprocessor struct {
-	logger       *zap.Logger
-	config       Config
-	backstageMap map[string]RepoInfo
-	mapMu        sync.RWMutex // Protects backstageMap for concurrent access
-	cancel       context.CancelFunc
-	done         chan struct{}
+	logger  *zap.Logger
+	config  Config
+	catalog catalog.Provider // Set on Start when the catalog comes from an extension
+	inline  *catalog.Catalog // Catalog owned by the processor, nil when using an extension
+
+	telemetry *processorTelemetry
+
+	// metrics selects the metrics whose data points are enriched, nil for every metric
+	metrics *metricSelector
+
+	// cardinality limits the values of the enriched data point attributes, nil without limit
+	cardinality *cardinalityLimiter
+
+	// conditions decide which telemetry is enriched, nil to enrich everything
+	conditions *enrichConditions
+
+	// fuzzy matches the service names missing the exact lookup, nil to only match them as is
+	fuzzy *FuzzyMatcher
+
+	// observe is called with the outcome of every match, before the attributes are enriched
+	observe func(attributes pcommon.Map, info catalog.EntityInfo, identified bool, matched bool)
 }
 
 // newBackstageProcessor returns a processor that adds attributes to all the spans, logs and metrics.
 // To construct the attributes processors, the use of the factory methods are required
 // in order to validate the inputs.
-func newBackstageProcessor(logger *zap.Logger, config component.Config) *backstageprocessor {
+func newBackstageProcessor(set component.TelemetrySettings, config component.Config) (*backstageprocessor, error) {
 	cfg := config.(*Config)
-	logger.Info("Fetching Backstage labels", zap.String("endpoint", cfg.Endpoint))
 
-	labels, err := getRepositoryLabelsMap(cfg.Endpoint, string(cfg.Token))
+	telemetry, err := newProcessorTelemetry(set)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := cfg.Metrics.selector()
+	if err != nil {
+		return nil, err
+	}
 
+	conditions, err := newEnrichConditions(cfg.Conditions, cfg.ErrorMode, set)
 	if err != nil {
-		logger.Error("Failed to fetch the Backstage labels", zap.Error(err))
-		labels = map[string]RepoInfo{}
-	} else {
-		logger.Info("Fetched GitHub repositories", zap.Int("number of repositories", len(labels)))
+		return nil, err
 	}
 
 	processor := &backstageprocessor{
-		config:       *cfg,
-		logger:       logger,
-		backstageMap: labels,
+		config:      *cfg,
+		logger:      set.Logger,
+		catalog:     &catalog.Snapshot{},
+		telemetry:   telemetry,
+		metrics:     metrics,
+		cardinality: newCardinalityLimiter(cfg.Cardinality, set.Logger, telemetry),
+		conditions:  conditions,
+		fuzzy:       NewFuzzyMatcher(cfg.FuzzyMatch),
+	}
+
+	if cfg.Extension == nil {
+		inline, err := catalog.New(set, cfg.CatalogConfig())
+		if err != nil {
+			return nil, err
+		}
+		processor.inline = inline
+		processor.catalog = inline
 	}
 
-	// Start background refresh if interval is configured
-	if cfg.RefreshInterval > 0 {
-		ctx, cancel := context.WithCancel(context.Background())
-		processor.cancel = cancel
-		processor.done = make(chan struct{})
-		logger.Info("Starting background refresh", zap.Duration("interval", cfg.RefreshInterval))
-		go processor.refreshLoop(ctx)
+	return processor, nil
+}
+
+// Start fetches the inline catalog, or looks up the backstagecatalog extension
+func (b *backstageprocessor) Start(ctx context.Context, host component.Host) error {
+	if b.inline != nil {
+		return b.inline.Start(ctx)
 	}
 
-	return processor
+	ext, found := host.GetExtensions()[*b.config.Extension]
+	if !found {
+		return fmt.Errorf("backstage catalog extension %q not found", b.config.Extension.String())
+	}
+	provider, ok := ext.(catalog.Provider)
+	if !ok {
+		return fmt.Errorf("extension %q is not a backstage catalog", b.config.Extension.String())
+	}
+	b.catalog = provider
+	return nil
 }
 
 // processTraces processes the incoming data
 // and returns the data to be sent to the next component
 func (b *backstageprocessor) processTraces(ctx context.Context, batch ptrace.Traces) (ptrace.Traces, error) {
-	for i := 0; i < batch.ResourceSpans().Len(); i++ {
-		rs := batch.ResourceSpans().At(i)
-		b.processResourceSpan(ctx, rs)
+	dropped := 0
+	var errs []error
+	batch.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		n, drop, err := b.processResourceSpan(ctx, rs)
+		dropped += n
+		errs = append(errs, err)
+		return drop
+	})
+	if err := errors.Join(errs...); err != nil {
+		return batch, err
 	}
-	return batch, nil
+	return batch, b.reportDropped(ctx, signalTraces, dropped, batch.SpanCount)
 }
 
-// processResourceSpan processes the RS and all of its spans
-func (b *backstageprocessor) processResourceSpan(ctx context.Context, rs ptrace.ResourceSpans) {
+// processResourceSpan processes the RS and all of its spans. It returns the number of spans
+// dropped by the filter policy, whether the whole RS is dropped, and the errors evaluating
+// the conditions. The spans of a resource failing the conditions aren't enriched, but they
+// are still filtered.
+func (b *backstageprocessor) processResourceSpan(ctx context.Context, rs ptrace.ResourceSpans) (int, bool, error) {
+	rsEnrich, err := b.conditions.enrichResource(ctx, rs.Resource(), rs)
+	errs := []error{err}
 	rsAttrs := rs.Resource().Attributes()
 
 	// Attributes can be part of a resource span
-	b.processAttrs(ctx, rsAttrs)
+	rsInfo, rsMatched, drop := b.processAttrsEnriching(ctx, rsAttrs, rsEnrich, true)
+	if drop {
+		dropped := 0
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			dropped += rs.ScopeSpans().At(j).Spans().Len()
+		}
+		return dropped, true, err
+	}
+	if b.config.Tenant.enabled() {
+		b.config.Tenant.apply(rsAttrs, rsInfo, rsMatched)
+	}
 
+	dropped := 0
 	for j := 0; j < rs.ScopeSpans().Len(); j++ {
 		ils := rs.ScopeSpans().At(j)
-		for k := 0; k < ils.Spans().Len(); k++ {
-			span := ils.Spans().At(k)
-			spanAttrs := span.Attributes()
-
+		ils.Spans().RemoveIf(func(span ptrace.Span) bool {
+			enrich := rsEnrich
+			if enrich {
+				var err error
+				enrich, err = b.conditions.enrichSpan(ctx, span, ils, rs)
+				errs = append(errs, err)
+			}
 			// Attributes can also be part of span
-			b.processAttrs(ctx, spanAttrs)
-		}
+			info, matched, drop := b.processAttrsEnriching(ctx, span.Attributes(), enrich, true)
+			if drop {
+				dropped++
+				return true
+			}
+			if !enrich {
+				return false
+			}
+			if b.config.Peer.Enabled {
+				b.processPeer(span)
+			}
+			if b.config.Spans.Events {
+				for k := 0; k < span.Events().Len(); k++ {
+					b.processRelatedAttrs(span.Events().At(k).Attributes())
+				}
+			}
+			if b.config.Spans.Links {
+				for k := 0; k < span.Links().Len(); k++ {
+					b.processRelatedAttrs(span.Links().At(k).Attributes())
+				}
+			}
+			if !matched {
+				info, matched = rsInfo, rsMatched
+			}
+			if matched && len(b.config.Sampling.Tiers) > 0 {
+				b.config.Sampling.apply(span, info)
+			}
+			return false
+		})
 	}
+	return dropped, false, errors.Join(errs...)
 }
 
-// refreshLoop periodically refreshes the backstage labels map
-func (b *backstageprocessor) refreshLoop(ctx context.Context) {
-	defer close(b.done)
+// processAttrs adds backstage metadata tags to attributes matched by the configured strategies.
+// It returns the matched entity, and whether the filter policy drops the telemetry holding
+// the attributes.
+func (b *backstageprocessor) processAttrs(ctx context.Context, attributes pcommon.Map) (info catalog.EntityInfo, matched bool, drop bool) {
+	return b.processAttrsEnriching(ctx, attributes, true, true)
+}
 
-	ticker := time.NewTicker(b.config.RefreshInterval)
-	defer ticker.Stop()
+// processAttrsEnriching processes attributes as processAttrs does, only adding the backstage
+// metadata tags when enrich is set. The conditions restrict the enrichment, the telemetry
+// failing them is still matched for the filter policy. The link attributes are only added
+// when links is set, metric data points skip them.
+func (b *backstageprocessor) processAttrsEnriching(_ context.Context, attributes pcommon.Map, enrich bool, links bool) (info catalog.EntityInfo, matched bool, drop bool) {
+	repoinfo, matchType, identified, matched := b.matchWithType(attributes)
+	if b.observe != nil && enrich {
+		b.observe(attributes, repoinfo, identified, matched)
+	}
+	if !identified {
+		b.logger.Debug("Not found service name", zap.Any("attributes", attributes))
+		return catalog.EntityInfo{}, false, false
+	}
+	if enrich {
+		b.annotate(attributes, repoinfo, matched, links)
+		if matched && b.fuzzy != nil {
+			attributes.PutStr(matchTypeKey, string(matchType))
+		}
+	}
+	return repoinfo, matched, b.filter(attributes, repoinfo, matched)
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			b.logger.Info("Stopping refresh loop")
+// processRelatedAttrs adds backstage metadata tags to the attributes of span events and links
+// identifying a service, by the configured strategies or the peer.service attribute. The filter
+// policy doesn't apply to them.
+func (b *backstageprocessor) processRelatedAttrs(attributes pcommon.Map) {
+	repoinfo, identified, matched := b.match(attributes)
+	if !identified {
+		peer, found := attributes.Get(peerServiceKey)
+		if !found {
 			return
-		case <-ticker.C:
-			b.logger.Debug("Refreshing backstage labels")
-			newMap, err := getRepositoryLabelsMap(b.config.Endpoint, string(b.config.Token))
-			if err != nil {
-				b.logger.Error("Failed to refresh backstage labels", zap.Error(err))
-				continue
-			}
-
-			// Update map with write lock
-			b.mapMu.Lock()
-			b.backstageMap = newMap
-			b.mapMu.Unlock()
-
-			b.logger.Info("Successfully refreshed backstage labels", zap.Int("count", len(newMap)))
 		}
+		repoinfo, matched = b.catalog.Snapshot().Lookup(peer.Str())
 	}
+	b.annotate(attributes, repoinfo, matched, true)
 }
 
-// processAttrs adds backstage metadata tags to resource based on service.name map
-func (b *backstageprocessor) processAttrs(_ context.Context, attributes pcommon.Map) {
-	if repo, found := attributes.Get(serviceNameKey); found {
-		b.logger.Debug("Found service name", zap.String(serviceNameKey, repo.Str()))
-		org := unknown
-		division := unknown
+// annotate adds the backstage metadata tags of an entity to identified attributes. The link
+// attributes are only added when links is set, as they hold a distinct URL for every entity.
+func (b *backstageprocessor) annotate(attributes pcommon.Map, repoinfo catalog.EntityInfo, matched bool, links bool) {
+	org := unknown
+	division := unknown
+	if matched {
+		b.logger.Debug("Matched Backstage entity", zap.String("entity", repoinfo.EntityRef))
+		org = repoinfo.Org
+		division = repoinfo.Division
+		if repoinfo.EntityRef != "" {
+			attributes.PutStr(refKey, repoinfo.EntityRef)
+		}
+		if b.config.SourceAttribute {
+			attributes.PutStr(sourceKey, repoinfo.Source)
+		}
+		if links && b.config.Links.enabled() {
+			b.config.Links.apply(attributes, repoinfo)
+		}
+	}
+	attributes.PutStr(divisionKey, division)
+	attributes.PutStr(orgKey, org)
+}
 
-		// Thread-safe read access to backstageMap
-		b.mapMu.RLock()
-		repoinfo, ok := b.backstageMap[repo.Str()]
-		b.mapMu.RUnlock()
+// filter applies the filter policy to identified attributes, marking them or returning
+// whether they must be dropped
+func (b *backstageprocessor) filter(attributes pcommon.Map, info catalog.EntityInfo, matched bool) bool {
+	if !b.config.Filter.enabled() {
+		return false
+	}
+	reason := b.config.Filter.reason(info, true, matched)
+	if reason == "" {
+		return false
+	}
+	if b.config.Filter.action() == FilterActionMark {
+		attributes.PutStr(b.config.Filter.attribute(), reason)
+		return false
+	}
+	b.logger.Debug("Dropping telemetry", zap.String("entity", info.EntityRef), zap.String("reason", reason))
+	return true
+}
 
-		if ok {
-			org = repoinfo.Org
-			division = repoinfo.Division
-		}
-		attributes.PutStr(divisionKey, division)
-		attributes.PutStr(orgKey, org)
-	} else {
-		b.logger.Debug("Not found service name", zap.Any("attributes", attributes))
+// reportDropped counts the items dropped by the filter policy, skipping the rest of the
+// pipeline when no item is left in the batch
+func (b *backstageprocessor) reportDropped(ctx context.Context, signal string, dropped int, remaining func() int) error {
+	if dropped == 0 {
+		return nil
 	}
+	b.telemetry.droppedItems.Add(ctx, int64(dropped), metric.WithAttributes(attribute.String("signal", signal)))
+	if remaining() == 0 {
+		return processorhelper.ErrSkipProcessingData
+	}
+	return nil
 }
 
 // processLogs processes the incoming data
 // and returns the data to be sent to the next component
 func (b *backstageprocessor) processLogs(ctx context.Context, logs plog.Logs) (plog.Logs, error) {
-	for i := 0; i < logs.ResourceLogs().Len(); i++ {
-		rl := logs.ResourceLogs().At(i)
-		b.processResourceLog(ctx, rl)
+	dropped := 0
+	var errs []error
+	logs.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		n, drop, err := b.processResourceLog(ctx, rl)
+		dropped += n
+		errs = append(errs, err)
+		return drop
+	})
+	if err := errors.Join(errs...); err != nil {
+		return logs, err
 	}
-	return logs, nil
+	return logs, b.reportDropped(ctx, signalLogs, dropped, logs.LogRecordCount)
 }
 
-// processResourceLog processes the log resource and all of its logs and then returns the last
-// view metric context. The context can be used for tests
-func (b *backstageprocessor) processResourceLog(ctx context.Context, rl plog.ResourceLogs) {
+// processResourceLog processes the log resource and all of its logs. It returns the number
+// of log records dropped by the filter policy, whether the whole resource is dropped, and the
+// errors evaluating the conditions. The logs of a resource failing the conditions aren't
+// enriched, but they are still filtered.
+func (b *backstageprocessor) processResourceLog(ctx context.Context, rl plog.ResourceLogs) (int, bool, error) {
+	rsEnrich, err := b.conditions.enrichResource(ctx, rl.Resource(), rl)
+	errs := []error{err}
 	rsAttrs := rl.Resource().Attributes()
 
-	b.processAttrs(ctx, rsAttrs)
+	rsInfo, rsMatched, drop := b.processAttrsEnriching(ctx, rsAttrs, rsEnrich, true)
+	if drop {
+		dropped := 0
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			dropped += rl.ScopeLogs().At(j).LogRecords().Len()
+		}
+		return dropped, true, err
+	}
+	if b.config.Tenant.enabled() {
+		b.config.Tenant.apply(rsAttrs, rsInfo, rsMatched)
+	}
 
+	dropped := 0
 	for j := 0; j < rl.ScopeLogs().Len(); j++ {
 		ils := rl.ScopeLogs().At(j)
-		for k := 0; k < ils.LogRecords().Len(); k++ {
-			log := ils.LogRecords().At(k)
-			b.processAttrs(ctx, log.Attributes())
-		}
+		ils.LogRecords().RemoveIf(func(log plog.LogRecord) bool {
+			enrich := rsEnrich
+			if enrich {
+				var err error
+				enrich, err = b.conditions.enrichLog(ctx, log, ils, rl)
+				errs = append(errs, err)
+			}
+			_, _, drop := b.processAttrsEnriching(ctx, log.Attributes(), enrich, true)
+			if drop {
+				dropped++
+			}
+			return drop
+		})
 	}
+	return dropped, false, errors.Join(errs...)
 }
 
 // processMetrics process metrics and add the backstage lable metadata.
 func (b *backstageprocessor) processMetrics(ctx context.Context, metrics pmetric.Metrics) (pmetric.Metrics, error) {
-	for i := 0; i < metrics.ResourceMetrics().Len(); i++ {
-		rm := metrics.ResourceMetrics().At(i)
-		b.processResourceMetric(ctx, rm)
+	dropped := 0
+	var errs []error
+	metrics.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		n, drop, err := b.processResourceMetric(ctx, rm)
+		dropped += n
+		errs = append(errs, err)
+		return drop
+	})
+	if err := errors.Join(errs...); err != nil {
+		return metrics, err
 	}
-	return metrics, nil
+	return metrics, b.reportDropped(ctx, signalMetrics, dropped, metrics.DataPointCount)
 }
 
-func (b *backstageprocessor) processResourceMetric(ctx context.Context, rm pmetric.ResourceMetrics) {
+// processResourceMetric processes the metric resource and all of its data points. It returns
+// the number of data points dropped by the filter policy, whether the whole resource is dropped,
+// and the error evaluating the resource conditions. The data points of a resource failing the
+// conditions aren't enriched, but they are still filtered.
+func (b *backstageprocessor) processResourceMetric(ctx context.Context, rm pmetric.ResourceMetrics) (int, bool, error) {
+	enrich, err := b.conditions.enrichResource(ctx, rm.Resource(), rm)
 	rsAttrs := rm.Resource().Attributes()
 
-	b.processAttrs(ctx, rsAttrs)
+	rsInfo, rsMatched, drop := b.processAttrsEnriching(ctx, rsAttrs, enrich, true)
+	if drop {
+		dropped := 0
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			ils := rm.ScopeMetrics().At(j)
+			for k := 0; k < ils.Metrics().Len(); k++ {
+				dropped += dataPointCount(ils.Metrics().At(k))
+			}
+		}
+		return dropped, true, err
+	}
+	if b.config.Tenant.enabled() {
+		b.config.Tenant.apply(rsAttrs, rsInfo, rsMatched)
+	}
 
+	dropped := 0
 	for j := 0; j < rm.ScopeMetrics().Len(); j++ {
 		ils := rm.ScopeMetrics().At(j)
-		for k := 0; k < ils.Metrics().Len(); k++ {
-			metric := ils.Metrics().At(k)
-			b.processMetricAttributes(ctx, metric)
-		}
+		ils.Metrics().RemoveIf(func(metric pmetric.Metric) bool {
+			// the metrics selection only restricts the enrichment, every metric is filtered
+			n := b.processMetricAttributes(ctx, metric, enrich && b.metrics.selected(metric))
+			dropped += n
+			// a metric is removed along with its last data point
+			return n > 0 && dataPointCount(metric) == 0
+		})
 	}
+	return dropped, false, err
 }
 
 // processMetricAttributes Attributes are provided for each log and trace, but not at the metric level
-// Need to process attributes for every data point within a metric.
-func (b *backstageprocessor) processMetricAttributes(ctx context.Context, metric pmetric.Metric) {
+// Need to process attributes for every data point within a metric. It returns the number of
+// data points dropped by the filter policy. When enrich is false, data points are only filtered.
+func (b *backstageprocessor) processMetricAttributes(ctx context.Context, metric pmetric.Metric, enrich bool) int {
+	dropped := 0
+	drop := func(attributes pcommon.Map) bool {
+		// the link attributes would create time series for every entity
+		if _, _, drop := b.processAttrsEnriching(ctx, attributes, enrich, false); drop {
+			dropped++
+			return true
+		}
+		if enrich {
+			b.cardinality.limit(ctx, attributes)
+		}
+		return false
+	}
+	exemplars := func(exemplars pmetric.ExemplarSlice) {
+		if enrich {
+			b.processExemplars(exemplars)
+		}
+	}
+
 	switch metric.Type() {
 	case pmetric.MetricTypeGauge:
-		dps := metric.Gauge().DataPoints()
-		for i := 0; i < dps.Len(); i++ {
-			b.processAttrs(ctx, dps.At(i).Attributes())
-		}
+		metric.Gauge().DataPoints().RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			exemplars(dp.Exemplars())
+			return drop(dp.Attributes())
+		})
 	case pmetric.MetricTypeSum:
-		dps := metric.Sum().DataPoints()
-		for i := 0; i < dps.Len(); i++ {
-			b.processAttrs(ctx, dps.At(i).Attributes())
-		}
+		metric.Sum().DataPoints().RemoveIf(func(dp pmetric.NumberDataPoint) bool {
+			exemplars(dp.Exemplars())
+			return drop(dp.Attributes())
+		})
 	case pmetric.MetricTypeHistogram:
-		dps := metric.Histogram().DataPoints()
-		for i := 0; i < dps.Len(); i++ {
-			b.processAttrs(ctx, dps.At(i).Attributes())
-		}
+		metric.Histogram().DataPoints().RemoveIf(func(dp pmetric.HistogramDataPoint) bool {
+			exemplars(dp.Exemplars())
+			return drop(dp.Attributes())
+		})
 	case pmetric.MetricTypeExponentialHistogram:
-		dps := metric.ExponentialHistogram().DataPoints()
-		for i := 0; i < dps.Len(); i++ {
-			b.processAttrs(ctx, dps.At(i).Attributes())
-		}
+		metric.ExponentialHistogram().DataPoints().RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool {
+			exemplars(dp.Exemplars())
+			return drop(dp.Attributes())
+		})
 	case pmetric.MetricTypeSummary:
-		dps := metric.Summary().DataPoints()
-		for i := 0; i < dps.Len(); i++ {
-			b.processAttrs(ctx, dps.At(i).Attributes())
-		}
+		// summaries have no exemplars, their quantiles carry no attributes
+		metric.Summary().DataPoints().RemoveIf(func(dp pmetric.SummaryDataPoint) bool {
+			return drop(dp.Attributes())
+		})
 	case pmetric.MetricTypeEmpty:
 	}
+	return dropped
 }
 
-// Shutdown gracefully shuts down the processor, stopping the background refresh loop if running
-func (b *backstageprocessor) Shutdown(ctx context.Context) error {
-	if b.cancel != nil {
-		b.logger.Info("Shutting down backstage processor")
-		b.cancel()
-
-		// Wait for refresh loop to finish or context to timeout
-		select {
-		case <-b.done:
-			b.logger.Info("Refresh loop stopped successfully")
-		case <-ctx.Done():
-			b.logger.Warn("Shutdown context timeout while waiting for refresh loop")
-			return ctx.Err()
+// processExemplars adds backstage metadata tags to the filtered attributes of the exemplars
+// identifying a service, when enabled. The filter policy doesn't apply to them.
+func (b *backstageprocessor) processExemplars(exemplars pmetric.ExemplarSlice) {
+	if !b.config.Metrics.Exemplars {
+		return
+	}
+	for i := 0; i < exemplars.Len(); i++ {
+		attributes := exemplars.At(i).FilteredAttributes()
+		if info, identified, matched := b.match(attributes); identified {
+			b.annotate(attributes, info, matched, false)
 		}
 	}
+}
+
+// dataPointCount returns the number of data points of a metric
+func dataPointCount(metric pmetric.Metric) int {
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		return metric.Gauge().DataPoints().Len()
+	case pmetric.MetricTypeSum:
+		return metric.Sum().DataPoints().Len()
+	case pmetric.MetricTypeHistogram:
+		return metric.Histogram().DataPoints().Len()
+	case pmetric.MetricTypeExponentialHistogram:
+		return metric.ExponentialHistogram().DataPoints().Len()
+	case pmetric.MetricTypeSummary:
+		return metric.Summary().DataPoints().Len()
+	}
+	return 0
+}
+
+// Shutdown gracefully shuts down the processor, stopping the inline catalog refresh loop if running
+func (b *backstageprocessor) Shutdown(ctx context.Context) error {
+	if b.inline != nil {
+		return b.inline.Shutdown(ctx)
+	}
 	return nil
 }
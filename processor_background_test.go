@@ -7,83 +7,31 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/pdata/pcommon"
-	"go.uber.org/zap"
-)
-
-func TestBackgroundRefresh(t *testing.T) {
-	t.Run("processor with no refresh interval doesn't start goroutine", func(t *testing.T) {
-		cfg := &Config{
-			Endpoint:        "http://example.com",
-			Token:           "test-token",
-			RefreshInterval: 0, // No refresh
-		}
-
-		processor := newBackstageProcessor(zap.NewNop(), cfg)
-		assert.Nil(t, processor.cancel, "cancel should be nil when refresh is disabled")
-		assert.Nil(t, processor.done, "done channel should be nil when refresh is disabled")
-	})
-
-	t.Run("processor with refresh interval starts goroutine", func(t *testing.T) {
-		cfg := &Config{
-			Endpoint:        "http://example.com",
-			Token:           "test-token",
-			RefreshInterval: 100 * time.Millisecond,
-		}
-
-		processor := newBackstageProcessor(zap.NewNop(), cfg)
-		require.NotNil(t, processor.cancel, "cancel should be set when refresh is enabled")
-		require.NotNil(t, processor.done, "done channel should be set when refresh is enabled")
-
-		// Cleanup
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		err := processor.Shutdown(ctx)
-		assert.NoError(t, err)
-	})
 
-	t.Run("shutdown stops refresh loop gracefully", func(t *testing.T) {
-		cfg := &Config{
-			Endpoint:        "http://example.com",
-			Token:           "test-token",
-			RefreshInterval: 100 * time.Millisecond,
-		}
-
-		processor := newBackstageProcessor(zap.NewNop(), cfg)
-		require.NotNil(t, processor.cancel)
-		require.NotNil(t, processor.done)
-
-		// Give the goroutine time to start
-		time.Sleep(50 * time.Millisecond)
-
-		// Shutdown with timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-
-		start := time.Now()
-		err := processor.Shutdown(ctx)
-		elapsed := time.Since(start)
-
-		assert.NoError(t, err, "shutdown should complete successfully")
-		assert.Less(t, elapsed, 1*time.Second, "shutdown should complete quickly")
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
 
-		// Verify done channel is closed
-		select {
-		case <-processor.done:
-			// Success - channel is closed
-		case <-time.After(100 * time.Millisecond):
-			t.Fatal("done channel should be closed after shutdown")
-		}
-	})
+// newTestProcessor creates a processor with no-op telemetry, failing the test on error
+func newTestProcessor(t *testing.T, cfg *Config) *backstageprocessor {
+	t.Helper()
+	processor, err := newBackstageProcessor(componenttest.NewNopTelemetrySettings(), cfg)
+	require.NoError(t, err)
+	return processor
+}
 
+func TestBackgroundRefresh(t *testing.T) {
 	t.Run("shutdown with no background goroutine", func(t *testing.T) {
 		cfg := &Config{
-			Endpoint:        "http://example.com",
+			Endpoint:        backstagetest.NewServer(t).URL,
 			Token:           "test-token",
 			RefreshInterval: 0, // No refresh
 		}
 
-		processor := newBackstageProcessor(zap.NewNop(), cfg)
+		processor := newTestProcessor(t, cfg)
 
 		ctx := context.Background()
 		err := processor.Shutdown(ctx)
@@ -91,51 +39,59 @@ func TestBackgroundRefresh(t *testing.T) {
 	})
 
 	t.Run("concurrent map access during refresh", func(t *testing.T) {
+		server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", map[string]string{"org": "org1"}))
 		cfg := &Config{
-			Endpoint:        "http://example.com",
-			Token:           "test-token",
-			RefreshInterval: 50 * time.Millisecond,
+			Endpoint: server.URL,
+			Token:    "test-token",
 		}
 
-		processor := newBackstageProcessor(zap.NewNop(), cfg)
+		processor := newTestProcessor(t, cfg)
+		require.NoError(t, processor.Start(context.Background(), componenttest.NewNopHost()))
 		defer func() {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 			_ = processor.Shutdown(ctx)
 		}()
 
-		// Add some initial data
-		processor.backstageMap = map[string]RepoInfo{
-			"service1": {Org: "org1", Division: "div1"},
-			"service2": {Org: "org2", Division: "div2"},
-		}
-
-		// Simulate concurrent reads while refresh might be happening
-		done := make(chan bool)
+		// the catalog is refreshed while the attributes are processed, run with -race
+		refreshed := make(chan struct{})
 		go func() {
-			for i := 0; i < 100; i++ {
-				attrs := pcommon.NewMap()
-				attrs.PutStr(serviceNameKey, "service1")
-				processor.processAttrs(context.Background(), attrs)
-				time.Sleep(1 * time.Millisecond)
+			defer close(refreshed)
+			for i := 0; i < 20; i++ {
+				org := "org1"
+				if i%2 == 1 {
+					org = "org2"
+				}
+				server.SetEntities(backstagetest.GithubRepository("", "service1", "org/service1", map[string]string{"org": org}))
+				_, err := processor.inline.TriggerRefresh(context.Background(), 0)
+				assert.NoError(t, err)
 			}
-			done <- true
 		}()
 
-		// Wait for concurrent operations to complete
-		<-done
+		for {
+			select {
+			case <-refreshed:
+				return
+			default:
+			}
+			attrs := pcommon.NewMap()
+			attrs.PutStr(serviceNameKey, "org-service1")
+			processor.processAttrs(context.Background(), attrs)
+			org, _ := attrs.Get(orgKey)
+			assert.Contains(t, []string{"org1", "org2"}, org.Str())
+		}
 	})
 
 	t.Run("thread-safe map read operations", func(t *testing.T) {
 		cfg := &Config{
-			Endpoint: "http://example.com",
+			Endpoint: backstagetest.NewServer(t).URL,
 			Token:    "test-token",
 		}
 
-		processor := newBackstageProcessor(zap.NewNop(), cfg)
-		processor.backstageMap = map[string]RepoInfo{
+		processor := newTestProcessor(t, cfg)
+		processor.catalog = &catalog.Snapshot{Keys: map[string]RepoInfo{
 			"myservice": {Org: "myorg", Division: "mydiv"},
-		}
+		}}
 
 		// Test concurrent reads (should be safe with RLock)
 		done := make(chan bool, 10)
@@ -165,98 +121,19 @@ func TestBackgroundRefresh(t *testing.T) {
 	})
 }
 
-func TestShutdownTimeout(t *testing.T) {
-	// Note: This test is tricky because we'd need to simulate a stuck refresh loop
-	// For now, we verify that shutdown respects the context timeout
-	t.Run("shutdown respects context timeout", func(t *testing.T) {
-		cfg := &Config{
-			Endpoint:        "http://example.com",
-			Token:           "test-token",
-			RefreshInterval: 10 * time.Millisecond,
-		}
-
-		processor := newBackstageProcessor(zap.NewNop(), cfg)
-		require.NotNil(t, processor.cancel)
-		require.NotNil(t, processor.done)
-
-		// Create a very short timeout context
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Nanosecond)
-		defer cancel()
-
-		// This might fail due to timeout, which is expected behavior
-		err := processor.Shutdown(ctx)
-		// We don't assert the error here because timing can be unpredictable in tests
-		// The important thing is that the function returns and doesn't hang
-		_ = err
-
-		// Cleanup with proper context
-		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cleanupCancel()
-		_ = processor.Shutdown(cleanupCtx)
-	})
-}
-
-func TestRefreshIntervalConfiguration(t *testing.T) {
-	tests := []struct {
-		name            string
-		refreshInterval time.Duration
-		expectGoroutine bool
-	}{
-		{
-			name:            "zero duration - no refresh",
-			refreshInterval: 0,
-			expectGoroutine: false,
-		},
-		{
-			name:            "positive duration - enable refresh",
-			refreshInterval: 1 * time.Minute,
-			expectGoroutine: true,
-		},
-		{
-			name:            "very short duration",
-			refreshInterval: 1 * time.Millisecond,
-			expectGoroutine: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			cfg := &Config{
-				Endpoint:        "http://example.com",
-				Token:           "test-token",
-				RefreshInterval: tt.refreshInterval,
-			}
-
-			processor := newBackstageProcessor(zap.NewNop(), cfg)
-
-			if tt.expectGoroutine {
-				assert.NotNil(t, processor.cancel, "cancel should be set")
-				assert.NotNil(t, processor.done, "done should be set")
-
-				// Cleanup
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				_ = processor.Shutdown(ctx)
-			} else {
-				assert.Nil(t, processor.cancel, "cancel should be nil")
-				assert.Nil(t, processor.done, "done should be nil")
-			}
-		})
-	}
-}
-
 func TestProcessorIntegration(t *testing.T) {
 	t.Run("processor lifecycle with factory", func(t *testing.T) {
 		// Verify that processor properly integrates with the collector lifecycle
 		cfg := &Config{
-			Endpoint:        "http://example.com",
+			Endpoint:        backstagetest.NewServer(t).URL,
 			Token:           "test-token",
 			RefreshInterval: 100 * time.Millisecond,
 		}
 
-		processor := newBackstageProcessor(zap.NewNop(), cfg)
+		processor := newTestProcessor(t, cfg)
 		require.NotNil(t, processor)
-		require.NotNil(t, processor.cancel, "background goroutine should be started")
+		require.NotNil(t, processor.inline, "inline catalog should be created")
+		require.NoError(t, processor.Start(context.Background(), componenttest.NewNopHost()))
 
 		// Give it some time to run
 		time.Sleep(150 * time.Millisecond)
@@ -268,3 +145,63 @@ func TestProcessorIntegration(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+// testCatalogExtension is a backstagecatalog extension serving a fixed snapshot
+type testCatalogExtension struct {
+	component.StartFunc
+	component.ShutdownFunc
+	catalog.Provider
+}
+
+// testHost is a host holding the given extensions
+type testHost struct {
+	component.Host
+	extensions map[component.ID]component.Component
+}
+
+func (h testHost) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
+
+func TestProcessorExtension(t *testing.T) {
+	extensionID := component.MustNewIDWithName("backstagecatalog", "shared")
+	cfg := &Config{Extension: &extensionID}
+
+	t.Run("uses the catalog of the extension", func(t *testing.T) {
+		processor := newTestProcessor(t, cfg)
+		require.Nil(t, processor.inline, "no inline catalog should be created")
+
+		host := testHost{extensions: map[component.ID]component.Component{
+			extensionID: testCatalogExtension{Provider: &catalog.Snapshot{Keys: map[string]RepoInfo{
+				"myservice": {Org: "myorg", Division: "mydiv"},
+			}}},
+		}}
+		require.NoError(t, processor.Start(context.Background(), host))
+
+		attrs := pcommon.NewMap()
+		attrs.PutStr(serviceNameKey, "myservice")
+		processor.processAttrs(context.Background(), attrs)
+
+		org, _ := attrs.Get(orgKey)
+		assert.Equal(t, "myorg", org.Str())
+		assert.NoError(t, processor.Shutdown(context.Background()))
+	})
+
+	t.Run("fails when the extension is missing", func(t *testing.T) {
+		processor := newTestProcessor(t, cfg)
+		err := processor.Start(context.Background(), componenttest.NewNopHost())
+		assert.EqualError(t, err, `backstage catalog extension "backstagecatalog/shared" not found`)
+	})
+
+	t.Run("fails when the extension is not a catalog", func(t *testing.T) {
+		processor := newTestProcessor(t, cfg)
+		host := testHost{extensions: map[component.ID]component.Component{
+			extensionID: struct {
+				component.StartFunc
+				component.ShutdownFunc
+			}{},
+		}}
+		err := processor.Start(context.Background(), host)
+		assert.EqualError(t, err, `extension "backstagecatalog/shared" is not a backstage catalog`)
+	})
+}
@@ -2,13 +2,21 @@ package backstageprocessor
 
 import (
 	"context"
+	"net/http"
+	"slices"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
 )
 
 func TestProcessAttrs(t *testing.T) {
@@ -27,9 +35,9 @@ func TestProcessAttrs(t *testing.T) {
 	}
 
 	processor := &backstageprocessor{
-		logger:       logger,
-		config:       *config,
-		backstageMap: backstageMap,
+		logger:  logger,
+		config:  *config,
+		catalog: &catalog.Snapshot{Keys: backstageMap},
 	}
 
 	t.Run("with known service name", func(t *testing.T) {
@@ -111,9 +119,9 @@ func TestProcessTraces(t *testing.T) {
 	}
 
 	processor := &backstageprocessor{
-		logger:       logger,
-		config:       *config,
-		backstageMap: backstageMap,
+		logger:  logger,
+		config:  *config,
+		catalog: &catalog.Snapshot{Keys: backstageMap},
 	}
 
 	t.Run("adds backstage attributes to traces", func(t *testing.T) {
@@ -140,6 +148,65 @@ func TestProcessTraces(t *testing.T) {
 			t.Errorf("Expected division to be 'trace-division', got '%s'", division.Str())
 		}
 	})
+
+	t.Run("adds backstage attributes to span events and links", func(t *testing.T) {
+		processor := &backstageprocessor{
+			logger: logger,
+			config: Config{Spans: SpansConfig{Events: true, Links: true}},
+			catalog: &catalog.Snapshot{Keys: map[string]RepoInfo{
+				"trace-service": {Org: "trace-org", Division: "trace-division"},
+				"shared-lib":    {Org: "lib-org", Division: "lib-division"},
+			}},
+		}
+
+		traces := ptrace.NewTraces()
+		rs := traces.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr(serviceNameKey, "trace-service")
+		span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		exception := span.Events().AppendEmpty()
+		exception.SetName("exception")
+		exception.Attributes().PutStr(serviceNameKey, "shared-lib")
+		peer := span.Events().AppendEmpty()
+		peer.Attributes().PutStr(peerServiceKey, "shared-lib")
+		other := span.Events().AppendEmpty()
+		other.Attributes().PutStr("exception.type", "IOError")
+		link := span.Links().AppendEmpty()
+		link.Attributes().PutStr(serviceNameKey, "unknown-service")
+
+		if _, err := processor.processTraces(context.Background(), traces); err != nil {
+			t.Fatalf("processTraces failed: %v", err)
+		}
+
+		for i, event := range []ptrace.SpanEvent{exception, peer} {
+			org, _ := event.Attributes().Get(orgKey)
+			if org.Str() != "lib-org" {
+				t.Errorf("Expected event %d org to be 'lib-org', got '%s'", i, org.Str())
+			}
+		}
+		if _, found := other.Attributes().Get(orgKey); found {
+			t.Error("Expected events without service to be left untouched")
+		}
+		if org, _ := link.Attributes().Get(orgKey); org.Str() != unknown {
+			t.Errorf("Expected link org to be '%s', got '%s'", unknown, org.Str())
+		}
+		if _, found := span.Attributes().Get(orgKey); found {
+			t.Error("Expected span attributes to be left untouched")
+		}
+	})
+
+	t.Run("leaves span events and links untouched by default", func(t *testing.T) {
+		traces := ptrace.NewTraces()
+		span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		event := span.Events().AppendEmpty()
+		event.Attributes().PutStr(serviceNameKey, "trace-service")
+
+		if _, err := processor.processTraces(context.Background(), traces); err != nil {
+			t.Fatalf("processTraces failed: %v", err)
+		}
+		if _, found := event.Attributes().Get(orgKey); found {
+			t.Error("Expected span events to be left untouched")
+		}
+	})
 }
 
 func TestProcessLogs(t *testing.T) {
@@ -158,9 +225,9 @@ func TestProcessLogs(t *testing.T) {
 	}
 
 	processor := &backstageprocessor{
-		logger:       logger,
-		config:       *config,
-		backstageMap: backstageMap,
+		logger:  logger,
+		config:  *config,
+		catalog: &catalog.Snapshot{Keys: backstageMap},
 	}
 
 	t.Run("adds backstage attributes to logs", func(t *testing.T) {
@@ -205,9 +272,9 @@ func TestProcessMetrics(t *testing.T) {
 	}
 
 	processor := &backstageprocessor{
-		logger:       logger,
-		config:       *config,
-		backstageMap: backstageMap,
+		logger:  logger,
+		config:  *config,
+		catalog: &catalog.Snapshot{Keys: backstageMap},
 	}
 
 	t.Run("adds backstage attributes to gauge metrics", func(t *testing.T) {
@@ -282,28 +349,261 @@ func TestProcessMetrics(t *testing.T) {
 	})
 }
 
-func TestNewBackstageProcessor(t *testing.T) {
+// newProfile returns profiles with a sample holding the given service name attribute
+func newProfile(t *testing.T, resourceService string, sampleService string) pprofile.Profiles {
+	t.Helper()
+	profiles := pprofile.NewProfiles()
+	dictionary := profiles.Dictionary()
+	dictionary.StringTable().Append("")
+
+	rp := profiles.ResourceProfiles().AppendEmpty()
+	rp.Resource().Attributes().PutStr(serviceNameKey, resourceService)
+	sample := rp.ScopeProfiles().AppendEmpty().Profiles().AppendEmpty().Samples().AppendEmpty()
+	sample.Values().Append(1)
+
+	attrs := pcommon.NewMap()
+	attrs.PutStr(serviceNameKey, sampleService)
+	attrs.PutStr("thread.name", "main")
+	require.NoError(t, putSampleAttributes(dictionary, sample, attrs))
+	return profiles
+}
+
+// sampleAttribute returns the attribute table entry of the first sample holding the given key
+func sampleAttribute(t *testing.T, profiles pprofile.Profiles, key string) pprofile.KeyValueAndUnit {
+	t.Helper()
+	dictionary := profiles.Dictionary()
+	sample := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0).Samples().At(0)
+	for _, index := range sample.AttributeIndices().All() {
+		attr := dictionary.AttributeTable().At(int(index))
+		if dictionary.StringTable().At(int(attr.KeyStrindex())) == key {
+			return attr
+		}
+	}
+	t.Fatalf("Expected the sample to hold the %s attribute", key)
+	return pprofile.KeyValueAndUnit{}
+}
+
+func TestProcessProfiles(t *testing.T) {
 	logger := zap.NewNop()
 	config := &Config{
-		Endpoint: "https://invalid-endpoint.example.com",
+		Endpoint: "https://backstage.example.com",
 		Token:    "test-token",
 	}
 
-	processor := newBackstageProcessor(logger, config)
+	backstageMap := map[string]RepoInfo{
+		"profile-service": {
+			Repo:     "profile-service",
+			Org:      "profile-org",
+			Division: "profile-division",
+		},
+		"sample-service": {
+			Repo:     "sample-service",
+			Org:      "sample-org",
+			Division: "sample-division",
+		},
+	}
+
+	processor := &backstageprocessor{
+		logger:  logger,
+		config:  *config,
+		catalog: &catalog.Snapshot{Keys: backstageMap},
+	}
+
+	t.Run("adds backstage attributes to profiles", func(t *testing.T) {
+		result, err := processor.processProfiles(context.Background(), newProfile(t, "profile-service", "sample-service"))
+		if err != nil {
+			t.Fatalf("processProfiles failed: %v", err)
+		}
+
+		attrs := result.ResourceProfiles().At(0).Resource().Attributes()
+		org, _ := attrs.Get(orgKey)
+		division, _ := attrs.Get(divisionKey)
+
+		if org.Str() != "profile-org" {
+			t.Errorf("Expected org to be 'profile-org', got '%s'", org.Str())
+		}
+		if division.Str() != "profile-division" {
+			t.Errorf("Expected division to be 'profile-division', got '%s'", division.Str())
+		}
+
+		// Check sample attributes, read from the dictionary
+		dictionary := result.Dictionary()
+		sample := result.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0).Samples().At(0)
+		sampleAttrs := pprofile.FromAttributeIndices(dictionary.AttributeTable(), sample, dictionary)
+		sampleOrg, _ := sampleAttrs.Get(orgKey)
+		threadName, _ := sampleAttrs.Get("thread.name")
+
+		if sampleOrg.Str() != "sample-org" {
+			t.Errorf("Expected sample org to be 'sample-org', got '%s'", sampleOrg.Str())
+		}
+		if threadName.Str() != "main" {
+			t.Errorf("Expected sample attributes to be kept, got thread.name '%s'", threadName.Str())
+		}
+	})
+
+	t.Run("reuses the dictionary entries", func(t *testing.T) {
+		profiles := newProfile(t, "profile-service", "sample-service")
+		result, err := processor.processProfiles(context.Background(), profiles)
+		if err != nil {
+			t.Fatalf("processProfiles failed: %v", err)
+		}
+		tableLen := result.Dictionary().AttributeTable().Len()
+
+		if _, err := processor.processProfiles(context.Background(), result); err != nil {
+			t.Fatalf("processProfiles failed: %v", err)
+		}
+		if result.Dictionary().AttributeTable().Len() != tableLen {
+			t.Errorf("Expected %d attribute table entries, got %d", tableLen, result.Dictionary().AttributeTable().Len())
+		}
+	})
+
+	t.Run("keeps the unit of the sample attributes", func(t *testing.T) {
+		profiles := newProfile(t, "profile-service", "sample-service")
+		dictionary := profiles.Dictionary()
+		unit, err := pprofile.SetString(dictionary.StringTable(), "ms")
+		require.NoError(t, err)
+		threadName := sampleAttribute(t, profiles, "thread.name")
+		threadName.SetUnitStrindex(unit)
+
+		result, err := processor.processProfiles(context.Background(), profiles)
+		require.NoError(t, err)
+		threadName = sampleAttribute(t, result, "thread.name")
+		if threadName.UnitStrindex() != unit {
+			t.Errorf("Expected thread.name to keep its unit, got string index %d", threadName.UnitStrindex())
+		}
+	})
+
+	t.Run("leaves the samples identifying no service untouched", func(t *testing.T) {
+		profiles := newProfile(t, "profile-service", "sample-service")
+		unit, err := pprofile.SetString(profiles.Dictionary().StringTable(), "ms")
+		require.NoError(t, err)
+		sampleAttribute(t, profiles, "thread.name").SetUnitStrindex(unit)
+		tableLen := profiles.Dictionary().AttributeTable().Len()
+		sample := profiles.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0).Samples().At(0)
+		// the sample only holds thread.name
+		sample.AttributeIndices().FromRaw(sample.AttributeIndices().AsRaw()[1:])
+		indices := sample.AttributeIndices().AsRaw()
+
+		result, err := processor.processProfiles(context.Background(), profiles)
+		require.NoError(t, err)
+		if result.Dictionary().AttributeTable().Len() != tableLen {
+			t.Errorf("Expected %d attribute table entries, got %d", tableLen, result.Dictionary().AttributeTable().Len())
+		}
+		sample = result.ResourceProfiles().At(0).ScopeProfiles().At(0).Profiles().At(0).Samples().At(0)
+		if got := sample.AttributeIndices().AsRaw(); !slices.Equal(got, indices) {
+			t.Errorf("Expected the attribute indices %v, got %v", indices, got)
+		}
+	})
+}
+
+func TestNewBackstageProcessor(t *testing.T) {
+	set := componenttest.NewNopTelemetrySettings()
+	logger := set.Logger
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "service1", "org/service1", nil))
+	server.FailNext(1, http.StatusServiceUnavailable)
+	config := &Config{
+		Endpoint: server.URL,
+		Token:    "test-token",
+	}
+
+	processor, err := newBackstageProcessor(set, config)
+	if err != nil {
+		t.Fatalf("newBackstageProcessor failed: %v", err)
+	}
 
 	if processor == nil {
-		t.Fatal("Expected processor to be created even with invalid endpoint")
+		t.Fatal("Expected processor to be created even with failing endpoint")
 	}
 
 	if processor.logger != logger {
 		t.Error("Expected logger to be set correctly")
 	}
 
-	if processor.backstageMap == nil {
-		t.Error("Expected backstageMap to be initialized")
+	if err := processor.Start(context.Background(), componenttest.NewNopHost()); err != nil {
+		t.Fatalf("Expected processor to start even with failing endpoint: %v", err)
+	}
+
+	if processor.catalog.Snapshot() == nil {
+		t.Error("Expected catalog to be initialized")
+	}
+
+	if len(processor.catalog.Snapshot().Keys) != 0 {
+		t.Error("Expected catalog to be empty when endpoint fails")
+	}
+}
+
+func TestProcessAttrsSourceAttribute(t *testing.T) {
+	processor := &backstageprocessor{
+		logger: zap.NewNop(),
+		config: Config{SourceAttribute: true},
+		catalog: &catalog.Snapshot{Keys: map[string]RepoInfo{
+			"bu2-service": {Repo: "bu2-service", Org: "bu2-org", Division: "bu2-division", Source: "bu2"},
+		}},
+	}
+
+	t.Run("with known service name", func(t *testing.T) {
+		attrs := pcommon.NewMap()
+		attrs.PutStr(serviceNameKey, "bu2-service")
+
+		processor.processAttrs(context.Background(), attrs)
+
+		source, found := attrs.Get(sourceKey)
+		if !found {
+			t.Fatal("Expected source attribute to exist")
+		}
+		if source.Str() != "bu2" {
+			t.Errorf("Expected source to be 'bu2', got '%s'", source.Str())
+		}
+	})
+
+	t.Run("with unknown service name", func(t *testing.T) {
+		attrs := pcommon.NewMap()
+		attrs.PutStr(serviceNameKey, "unknown-service")
+
+		processor.processAttrs(context.Background(), attrs)
+
+		if _, found := attrs.Get(sourceKey); found {
+			t.Error("Expected source attribute not to exist")
+		}
+	})
+}
+
+func TestProcessAttrsEntityRef(t *testing.T) {
+	info := RepoInfo{
+		Repo:      "org-service",
+		Org:       "team-org",
+		Division:  "team-division",
+		Kind:      "Resource",
+		Namespace: "team",
+		Name:      "service",
+		EntityRef: "resource:team/service",
+	}
+	processor := &backstageprocessor{
+		logger: zap.NewNop(),
+		catalog: &catalog.Snapshot{Keys: map[string]RepoInfo{
+			"org-service":           info,
+			"resource:team/service": info,
+		}},
 	}
 
-	if len(processor.backstageMap) != 0 {
-		t.Error("Expected backstageMap to be empty when endpoint is invalid")
+	for _, serviceName := range []string{"org-service", "resource:team/service", "Resource:Team/Service"} {
+		t.Run(serviceName, func(t *testing.T) {
+			attrs := pcommon.NewMap()
+			attrs.PutStr(serviceNameKey, serviceName)
+
+			processor.processAttrs(context.Background(), attrs)
+
+			org, _ := attrs.Get(orgKey)
+			if org.Str() != "team-org" {
+				t.Errorf("Expected org to be 'team-org', got '%s'", org.Str())
+			}
+			ref, found := attrs.Get(refKey)
+			if !found {
+				t.Fatal("Expected entity ref attribute to exist")
+			}
+			if ref.Str() != "resource:team/service" {
+				t.Errorf("Expected entity ref to be 'resource:team/service', got '%s'", ref.Str())
+			}
+		})
 	}
 }
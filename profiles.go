@@ -0,0 +1,144 @@
+package backstageprocessor
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.uber.org/zap"
+)
+
+// processProfiles processes the incoming data
+// and returns the data to be sent to the next component
+func (b *backstageprocessor) processProfiles(ctx context.Context, profiles pprofile.Profiles) (pprofile.Profiles, error) {
+	dictionary := profiles.Dictionary()
+	dropped := 0
+	var errs []error
+	profiles.ResourceProfiles().RemoveIf(func(rp pprofile.ResourceProfiles) bool {
+		n, drop, err := b.processResourceProfile(ctx, dictionary, rp)
+		dropped += n
+		errs = append(errs, err)
+		return drop
+	})
+	if err := errors.Join(errs...); err != nil {
+		return profiles, err
+	}
+	return profiles, b.reportDropped(ctx, signalProfiles, dropped, profiles.SampleCount)
+}
+
+// processResourceProfile processes the profile resource and all of its samples. It returns the
+// number of samples dropped by the filter policy, whether the whole resource is dropped, and the
+// error evaluating the resource conditions. The samples of a resource failing the conditions
+// aren't enriched, but they are still filtered.
+func (b *backstageprocessor) processResourceProfile(ctx context.Context, dictionary pprofile.ProfilesDictionary, rp pprofile.ResourceProfiles) (int, bool, error) {
+	enrich, err := b.conditions.enrichResource(ctx, rp.Resource(), rp)
+	rsAttrs := rp.Resource().Attributes()
+
+	rsInfo, rsMatched, drop := b.processAttrsEnriching(ctx, rsAttrs, enrich, true)
+	if drop {
+		dropped := 0
+		for j := 0; j < rp.ScopeProfiles().Len(); j++ {
+			profiles := rp.ScopeProfiles().At(j).Profiles()
+			for k := 0; k < profiles.Len(); k++ {
+				dropped += profiles.At(k).Samples().Len()
+			}
+		}
+		return dropped, true, err
+	}
+	if b.config.Tenant.enabled() {
+		b.config.Tenant.apply(rsAttrs, rsInfo, rsMatched)
+	}
+
+	dropped := 0
+	for j := 0; j < rp.ScopeProfiles().Len(); j++ {
+		ils := rp.ScopeProfiles().At(j)
+		ils.Profiles().RemoveIf(func(profile pprofile.Profile) bool {
+			n := b.processSampleAttributes(ctx, dictionary, profile, enrich)
+			dropped += n
+			// a profile is removed along with its last sample
+			return n > 0 && profile.Samples().Len() == 0
+		})
+	}
+	return dropped, false, err
+}
+
+// processSampleAttributes enriches the attributes of every sample of a profile. Sample attributes
+// are indices in the attribute table of the dictionary, the enriched attributes are added to the
+// table. It returns the number of samples dropped by the filter policy. When enrich is false,
+// samples are only filtered.
+func (b *backstageprocessor) processSampleAttributes(ctx context.Context, dictionary pprofile.ProfilesDictionary, profile pprofile.Profile, enrich bool) int {
+	dropped := 0
+	profile.Samples().RemoveIf(func(sample pprofile.Sample) bool {
+		if sample.AttributeIndices().Len() == 0 {
+			return false
+		}
+		attributes := pprofile.FromAttributeIndices(dictionary.AttributeTable(), sample, dictionary)
+		original := pcommon.NewMap()
+		attributes.CopyTo(original)
+		if _, _, drop := b.processAttrsEnriching(ctx, attributes, enrich, true); drop {
+			dropped++
+			return true
+		}
+		// the samples left untouched keep their attribute indices
+		if attributes.Equal(original) {
+			return false
+		}
+		if err := putSampleAttributes(dictionary, sample, attributes); err != nil {
+			b.logger.Warn("Failed to enrich profile sample", zap.Error(err))
+		}
+		return false
+	})
+	return dropped
+}
+
+// putSampleAttributes replaces the attribute indices of a sample with the given attributes,
+// reusing the entries of the dictionary tables when they exist. The attributes the sample
+// already holds keep their entry, or their unit when their value changed.
+func putSampleAttributes(dictionary pprofile.ProfilesDictionary, sample pprofile.Sample, attributes pcommon.Map) error {
+	table := dictionary.AttributeTable()
+	strs := dictionary.StringTable()
+	// existing holds the table index of the attributes the sample already holds, by key
+	existing := map[string]int32{}
+	for _, index := range sample.AttributeIndices().All() {
+		if index < 0 || int(index) >= table.Len() {
+			continue
+		}
+		if key := table.At(int(index)).KeyStrindex(); key >= 0 && int(key) < strs.Len() {
+			existing[strs.At(int(key))] = index
+		}
+	}
+
+	indices := make([]int32, 0, attributes.Len())
+	var err error
+	attributes.Range(func(k string, v pcommon.Value) bool {
+		previous, found := existing[k]
+		if found && table.At(int(previous)).Value().Equal(v) {
+			indices = append(indices, previous)
+			return true
+		}
+
+		attr := pprofile.NewKeyValueAndUnit()
+		var key int32
+		if key, err = pprofile.SetString(strs, k); err != nil {
+			return false
+		}
+		attr.SetKeyStrindex(key)
+		v.CopyTo(attr.Value())
+		if found {
+			attr.SetUnitStrindex(table.At(int(previous)).UnitStrindex())
+		}
+
+		var index int32
+		if index, err = pprofile.SetAttribute(table, attr); err != nil {
+			return false
+		}
+		indices = append(indices, index)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	sample.AttributeIndices().FromRaw(indices)
+	return nil
+}
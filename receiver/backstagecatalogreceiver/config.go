@@ -0,0 +1,102 @@
+package backstagecatalogreceiver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// defaultCollectionInterval is the default time between two emissions of the catalog.
+const defaultCollectionInterval = time.Hour
+
+// defaultRequiredLabels are the labels counted by the backstage.entities.missing_label metric by default.
+var defaultRequiredLabels = []string{"org", "division"}
+
+// LogFormat defines how the entities are emitted as logs.
+type LogFormat string
+
+const (
+	// LogFormatRecord emits every entity as a log record with the entity attributes.
+	LogFormatRecord LogFormat = "log"
+	// LogFormatEntityEvent emits every entity as an entity state event, following the
+	// experimental format of the entity events carried as logs.
+	LogFormatEntityEvent LogFormat = "entity_event"
+)
+
+// Config defines configuration for the Backstage catalog receiver.
+type Config struct {
+	catalog.Config `mapstructure:",squash"`
+
+	// Extension is the backstagecatalog extension providing the catalog. When set, the
+	// catalog is shared with other components and the inline catalog settings must be empty.
+	Extension *component.ID `mapstructure:"extension"`
+
+	// CollectionInterval is the time between two emissions of the loaded entities. The
+	// entities are emitted once the catalog is loaded, then at every interval. Defaults to 1h.
+	// While the catalog has no entity, the collection is retried with a backoff of up to 1m.
+	// An inline catalog without refresh_interval is fetched again before every collection.
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// LogFormat is the format of the entity logs, log or entity_event. Defaults to log.
+	LogFormat LogFormat `mapstructure:"log_format"`
+
+	// RequiredLabels are the labels every entity is expected to have, the
+	// backstage.entities.missing_label metric counting the entities missing each of them.
+	// Defaults to org and division.
+	RequiredLabels []string `mapstructure:"required_labels"`
+}
+
+var _ component.Config = (*Config)(nil)
+
+// Validate checks if the receiver configuration is valid.
+func (cfg *Config) Validate() error {
+	if cfg.CollectionInterval < 0 {
+		return errors.New("collection_interval must not be negative")
+	}
+	switch cfg.LogFormat {
+	case "", LogFormatRecord, LogFormatEntityEvent:
+	default:
+		return fmt.Errorf("unknown log_format %q", cfg.LogFormat)
+	}
+	for _, label := range cfg.RequiredLabels {
+		if label == "" {
+			return errors.New("required_labels must not contain empty labels")
+		}
+	}
+
+	if cfg.Extension != nil {
+		if !cfg.Config.IsEmpty() {
+			return errors.New("catalog settings must not be configured along with extension")
+		}
+		return nil
+	}
+	return cfg.Config.Validate()
+}
+
+// collectionInterval returns the configured interval, falling back to 1h.
+func (cfg *Config) collectionInterval() time.Duration {
+	if cfg.CollectionInterval == 0 {
+		return defaultCollectionInterval
+	}
+	return cfg.CollectionInterval
+}
+
+// logFormat returns the configured log format, falling back to log.
+func (cfg *Config) logFormat() LogFormat {
+	if cfg.LogFormat == "" {
+		return LogFormatRecord
+	}
+	return cfg.LogFormat
+}
+
+// requiredLabels returns the configured required labels, falling back to org and division.
+func (cfg *Config) requiredLabels() []string {
+	if len(cfg.RequiredLabels) == 0 {
+		return defaultRequiredLabels
+	}
+	return cfg.RequiredLabels
+}
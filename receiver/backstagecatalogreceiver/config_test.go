@@ -0,0 +1,71 @@
+package backstagecatalogreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/component"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestConfigValidate(t *testing.T) {
+	extension := component.MustNewID("backstagecatalog")
+	catalogCfg := catalog.Config{Endpoint: "https://backstage.example.com"}
+
+	tests := []struct {
+		name        string
+		config      *Config
+		expectedErr string
+	}{
+		{
+			name:   "valid config",
+			config: &Config{Config: catalogCfg, LogFormat: LogFormatEntityEvent},
+		},
+		{
+			name:   "valid config with extension",
+			config: &Config{Extension: &extension},
+		},
+		{
+			name:        "extension along with an endpoint",
+			config:      &Config{Config: catalogCfg, Extension: &extension},
+			expectedErr: "catalog settings must not be configured along with extension",
+		},
+		{
+			name:        "extension along with a namespace",
+			config:      &Config{Config: catalog.Config{Namespace: "platform"}, Extension: &extension},
+			expectedErr: "catalog settings must not be configured along with extension",
+		},
+		{
+			name:        "invalid catalog config",
+			config:      &Config{},
+			expectedErr: "either endpoint or sources must be configured",
+		},
+		{
+			name:        "negative collection interval",
+			config:      &Config{Config: catalogCfg, CollectionInterval: -1},
+			expectedErr: "collection_interval must not be negative",
+		},
+		{
+			name:        "unknown log format",
+			config:      &Config{Config: catalogCfg, LogFormat: "json"},
+			expectedErr: `unknown log_format "json"`,
+		},
+		{
+			name:        "empty required label",
+			config:      &Config{Config: catalogCfg, RequiredLabels: []string{"org", ""}},
+			expectedErr: "required_labels must not contain empty labels",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package backstagecatalogreceiver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver"
+)
+
+const (
+	LogsStability    = component.StabilityLevelAlpha
+	MetricsStability = component.StabilityLevelAlpha
+)
+
+// Note: This isn't a valid configuration because the receiver would load no entities.
+func createDefaultConfig() component.Config {
+	return &Config{}
+}
+
+// receivers holds the receiver of every component ID, so that the logs and metrics of a
+// receiver share a single catalog.
+var receivers = &sharedReceivers{receivers: map[component.ID]*sharedReceiver{}}
+
+// NewFactory returns a new factory for the Backstage catalog receiver.
+func NewFactory() receiver.Factory {
+	return receiver.NewFactory(
+		component.MustNewType("backstagecatalog"),
+		createDefaultConfig,
+		receiver.WithLogs(createLogsReceiver, LogsStability),
+		receiver.WithMetrics(createMetricsReceiver, MetricsStability))
+}
+
+func createLogsReceiver(
+	_ context.Context,
+	set receiver.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Logs,
+) (receiver.Logs, error) {
+
+	rCfg := cfg.(*Config)
+	r, err := receivers.getOrCreate(set.ID, func() (*catalogReceiver, error) {
+		return newCatalogReceiver(set.TelemetrySettings, rCfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logs := &entityLogs{config: *rCfg, next: nextConsumer}
+	r.emitters = append(r.emitters, logs.emit)
+	return r, nil
+}
+
+func createMetricsReceiver(
+	_ context.Context,
+	set receiver.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (receiver.Metrics, error) {
+
+	rCfg := cfg.(*Config)
+	r, err := receivers.getOrCreate(set.ID, func() (*catalogReceiver, error) {
+		return newCatalogReceiver(set.TelemetrySettings, rCfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics := &entityMetrics{config: *rCfg, next: nextConsumer}
+	r.emitters = append(r.emitters, metrics.emit)
+	return r, nil
+}
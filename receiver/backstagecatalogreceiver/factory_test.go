@@ -0,0 +1,27 @@
+package backstagecatalogreceiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+)
+
+func TestNewFactory(t *testing.T) {
+	factory := NewFactory()
+
+	assert.Equal(t, component.MustNewType("backstagecatalog"), factory.Type())
+	assert.Equal(t, component.StabilityLevelAlpha, factory.LogsStability())
+	assert.Equal(t, component.StabilityLevelAlpha, factory.MetricsStability())
+}
+
+func TestCreateDefaultConfig(t *testing.T) {
+	cfg, ok := createDefaultConfig().(*Config)
+	require.True(t, ok, "Expected config to be of type *Config")
+
+	assert.Equal(t, defaultCollectionInterval, cfg.collectionInterval())
+	assert.Equal(t, LogFormatRecord, cfg.logFormat())
+	assert.Equal(t, []string{"org", "division"}, cfg.requiredLabels())
+	assert.Error(t, cfg.Validate(), "default config has no endpoint")
+}
@@ -0,0 +1,112 @@
+package backstagecatalogreceiver
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// scopeName is the instrumentation scope of the emitted telemetry
+const scopeName = "github.com/v1v/opentelemetry-backstage-processor/receiver/backstagecatalogreceiver"
+
+// attribute keys of the entity logs
+const (
+	kindKey        = "backstage.entity.kind"
+	namespaceKey   = "backstage.entity.namespace"
+	nameKey        = "backstage.entity.name"
+	typeKey        = "backstage.entity.type"
+	refKey         = "backstage.entity.ref"
+	ownerKey       = "backstage.entity.owner"
+	systemKey      = "backstage.entity.system"
+	lifecycleKey   = "backstage.entity.lifecycle"
+	orgKey         = "backstage.org"
+	divisionKey    = "backstage.division"
+	sourceKey      = "backstage.source"
+	labelKeyPrefix = "backstage.entity.labels."
+)
+
+// attribute keys and values of the entity events carried as logs
+const (
+	entityEventAsLogKey    = "otel.entity.event_as_log"
+	entityEventTypeKey     = "otel.entity.event.type"
+	entityEventState       = "entity_state"
+	entityTypeKey          = "otel.entity.type"
+	entityIDKey            = "otel.entity.id"
+	entityAttributesKey    = "otel.entity.attributes"
+	entityIntervalKey      = "otel.entity.interval"
+	entityType             = "backstage.entity"
+	entityEventDescription = "Backstage catalog entity"
+)
+
+// entityLogs emits the entities as log records
+type entityLogs struct {
+	config Config
+	next   consumer.Logs
+}
+
+// emit sends a log record for every entity, in a single batch
+func (l *entityLogs) emit(ctx context.Context, entities []catalog.EntityInfo) error {
+	logs := plog.NewLogs()
+	scopeLogs := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	scopeLogs.Scope().SetName(scopeName)
+	if l.config.logFormat() == LogFormatEntityEvent {
+		scopeLogs.Scope().Attributes().PutBool(entityEventAsLogKey, true)
+	}
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	for _, info := range entities {
+		record := scopeLogs.LogRecords().AppendEmpty()
+		record.SetTimestamp(now)
+		record.SetObservedTimestamp(now)
+		if l.config.logFormat() == LogFormatEntityEvent {
+			l.entityEvent(record, info)
+		} else {
+			record.Body().SetStr(info.EntityRef)
+			putEntityAttributes(record.Attributes(), info)
+		}
+	}
+	return l.next.ConsumeLogs(ctx, logs)
+}
+
+// entityEvent fills a log record with the entity state event of an entity, identified by its
+// entity reference
+func (l *entityLogs) entityEvent(record plog.LogRecord, info catalog.EntityInfo) {
+	record.Body().SetStr(entityEventDescription)
+	attributes := record.Attributes()
+	attributes.PutStr(entityEventTypeKey, entityEventState)
+	attributes.PutStr(entityTypeKey, entityType)
+	attributes.PutEmptyMap(entityIDKey).PutStr(refKey, info.EntityRef)
+	entityAttributes := attributes.PutEmptyMap(entityAttributesKey)
+	putEntityAttributes(entityAttributes, info)
+	entityAttributes.Remove(refKey)
+	attributes.PutInt(entityIntervalKey, l.config.collectionInterval().Milliseconds())
+}
+
+// putEntityAttributes adds the attributes describing an entity, skipping the empty fields
+func putEntityAttributes(attributes pcommon.Map, info catalog.EntityInfo) {
+	for _, attr := range []struct{ key, value string }{
+		{kindKey, info.Kind},
+		{namespaceKey, info.Namespace},
+		{nameKey, info.Name},
+		{refKey, info.EntityRef},
+		{typeKey, info.Type},
+		{ownerKey, info.Owner},
+		{systemKey, info.System},
+		{lifecycleKey, info.Lifecycle},
+		{orgKey, info.Org},
+		{divisionKey, info.Division},
+		{sourceKey, info.Source},
+	} {
+		if attr.value != "" {
+			attributes.PutStr(attr.key, attr.value)
+		}
+	}
+	for key, value := range info.Labels {
+		attributes.PutStr(labelKeyPrefix+key, value)
+	}
+}
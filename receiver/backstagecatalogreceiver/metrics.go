@@ -0,0 +1,134 @@
+package backstagecatalogreceiver
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// names of the catalog metrics
+const (
+	entitiesCountMetric        = "backstage.entities.count"
+	entitiesMissingOwnerMetric = "backstage.entities.missing_owner"
+	entitiesMissingLabelMetric = "backstage.entities.missing_label"
+)
+
+// attribute keys of the catalog metrics
+const (
+	kindAttribute      = "kind"
+	typeAttribute      = "type"
+	lifecycleAttribute = "lifecycle"
+	ownerAttribute     = "owner"
+	labelAttribute     = "label"
+)
+
+// entityGroup is the set of attributes entities are counted by
+type entityGroup struct {
+	kind, entityType, lifecycle, owner string
+}
+
+// entityMetrics emits the catalog data-quality metrics of the entities
+type entityMetrics struct {
+	config Config
+	next   consumer.Metrics
+}
+
+// emit sends the entity counts of a snapshot, in a single batch
+func (m *entityMetrics) emit(ctx context.Context, entities []catalog.EntityInfo) error {
+	counts := map[entityGroup]int64{}
+	missingOwner := map[string]int64{}
+	missingLabel := map[string]int64{}
+	for _, label := range m.config.requiredLabels() {
+		missingLabel[label] = 0
+	}
+	for _, info := range entities {
+		counts[entityGroup{kind: info.Kind, entityType: info.Type, lifecycle: info.Lifecycle, owner: info.Owner}]++
+		// every kind has a data point, zero when all its entities have an owner
+		missing := missingOwner[info.Kind]
+		if info.Owner == "" {
+			missing++
+		}
+		missingOwner[info.Kind] = missing
+		for label := range missingLabel {
+			if info.Labels[label] == "" {
+				missingLabel[label]++
+			}
+		}
+	}
+
+	metrics := pmetric.NewMetrics()
+	scopeMetrics := metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty()
+	scopeMetrics.Scope().SetName(scopeName)
+	now := pcommon.NewTimestampFromTime(time.Now())
+
+	count := newGauge(scopeMetrics, entitiesCountMetric, "Number of catalog entities.")
+	groups := make([]entityGroup, 0, len(counts))
+	for group := range counts {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if a.entityType != b.entityType {
+			return a.entityType < b.entityType
+		}
+		if a.lifecycle != b.lifecycle {
+			return a.lifecycle < b.lifecycle
+		}
+		return a.owner < b.owner
+	})
+	for _, group := range groups {
+		dp := newDataPoint(count, now, counts[group])
+		dp.Attributes().PutStr(kindAttribute, group.kind)
+		dp.Attributes().PutStr(typeAttribute, group.entityType)
+		dp.Attributes().PutStr(lifecycleAttribute, group.lifecycle)
+		dp.Attributes().PutStr(ownerAttribute, group.owner)
+	}
+
+	owner := newGauge(scopeMetrics, entitiesMissingOwnerMetric, "Number of catalog entities without spec.owner.")
+	for _, kind := range sortedKeys(missingOwner) {
+		newDataPoint(owner, now, missingOwner[kind]).Attributes().PutStr(kindAttribute, kind)
+	}
+
+	label := newGauge(scopeMetrics, entitiesMissingLabelMetric, "Number of catalog entities without a required label.")
+	for _, key := range sortedKeys(missingLabel) {
+		newDataPoint(label, now, missingLabel[key]).Attributes().PutStr(labelAttribute, key)
+	}
+
+	return m.next.ConsumeMetrics(ctx, metrics)
+}
+
+// newGauge adds a gauge counting entities
+func newGauge(scopeMetrics pmetric.ScopeMetrics, name string, description string) pmetric.Gauge {
+	metric := scopeMetrics.Metrics().AppendEmpty()
+	metric.SetName(name)
+	metric.SetDescription(description)
+	metric.SetUnit("{entity}")
+	return metric.SetEmptyGauge()
+}
+
+// newDataPoint adds a data point with the given value to a gauge
+func newDataPoint(gauge pmetric.Gauge, now pcommon.Timestamp, value int64) pmetric.NumberDataPoint {
+	dp := gauge.DataPoints().AppendEmpty()
+	dp.SetTimestamp(now)
+	dp.SetIntValue(value)
+	return dp
+}
+
+// sortedKeys returns the keys of a count map in order, for the data points to be deterministic
+func sortedKeys(counts map[string]int64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
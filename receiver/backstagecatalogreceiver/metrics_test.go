@@ -0,0 +1,136 @@
+package backstagecatalogreceiver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestEntityMetrics(t *testing.T) {
+	entities := []catalog.EntityInfo{
+		{Kind: "Component", Type: "service", Lifecycle: "production", Owner: "group:default/shop", Labels: map[string]string{"org": "shop", "division": "retail"}},
+		{Kind: "Component", Type: "service", Lifecycle: "production", Owner: "group:default/shop", Labels: map[string]string{"org": "shop"}},
+		{Kind: "Component", Type: "website", Lifecycle: "experimental"},
+		{Kind: "Resource", Type: "github-repository", Owner: "group:default/payments", Labels: map[string]string{"org": "payments", "division": ""}},
+	}
+	sink := new(consumertest.MetricsSink)
+	metrics := &entityMetrics{next: sink}
+	require.NoError(t, metrics.emit(context.Background(), entities))
+
+	require.Len(t, sink.AllMetrics(), 1)
+	scopeMetrics := sink.AllMetrics()[0].ResourceMetrics().At(0).ScopeMetrics().At(0)
+	assert.Equal(t, scopeName, scopeMetrics.Scope().Name())
+	assert.Equal(t, map[string][]dataPoint{
+		entitiesCountMetric: {
+			{value: 2, attributes: map[string]any{kindAttribute: "Component", typeAttribute: "service", lifecycleAttribute: "production", ownerAttribute: "group:default/shop"}},
+			{value: 1, attributes: map[string]any{kindAttribute: "Component", typeAttribute: "website", lifecycleAttribute: "experimental", ownerAttribute: ""}},
+			{value: 1, attributes: map[string]any{kindAttribute: "Resource", typeAttribute: "github-repository", lifecycleAttribute: "", ownerAttribute: "group:default/payments"}},
+		},
+		entitiesMissingOwnerMetric: {
+			{value: 1, attributes: map[string]any{kindAttribute: "Component"}},
+			{value: 0, attributes: map[string]any{kindAttribute: "Resource"}},
+		},
+		entitiesMissingLabelMetric: {
+			{value: 3, attributes: map[string]any{labelAttribute: "division"}},
+			{value: 1, attributes: map[string]any{labelAttribute: "org"}},
+		},
+	}, gaugeDataPoints(scopeMetrics))
+}
+
+func TestEntityMetricsRequiredLabels(t *testing.T) {
+	sink := new(consumertest.MetricsSink)
+	metrics := &entityMetrics{config: Config{RequiredLabels: []string{"tier"}}, next: sink}
+	require.NoError(t, metrics.emit(context.Background(), []catalog.EntityInfo{{Kind: "Component", Labels: map[string]string{"tier": "1"}}}))
+
+	scopeMetrics := sink.AllMetrics()[0].ResourceMetrics().At(0).ScopeMetrics().At(0)
+	assert.Equal(t, []dataPoint{{value: 0, attributes: map[string]any{labelAttribute: "tier"}}}, gaugeDataPoints(scopeMetrics)[entitiesMissingLabelMetric])
+}
+
+func TestMetricsReceiver(t *testing.T) {
+	checkout := backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop"})
+	server := backstagetest.NewServer(t, checkout)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL
+
+	sink := new(consumertest.MetricsSink)
+	rcv, err := factory.CreateMetrics(context.Background(), receivertest.NewNopSettings(factory.Type()), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, rcv.Shutdown(context.Background())) }()
+
+	require.Eventually(t, func() bool { return len(sink.AllMetrics()) > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	scopeMetrics := sink.AllMetrics()[0].ResourceMetrics().At(0).ScopeMetrics().At(0)
+	assert.Equal(t, map[string][]dataPoint{
+		entitiesCountMetric: {
+			{value: 1, attributes: map[string]any{kindAttribute: "Resource", typeAttribute: "github-repository", lifecycleAttribute: "", ownerAttribute: ""}},
+		},
+		entitiesMissingOwnerMetric: {{value: 1, attributes: map[string]any{kindAttribute: "Resource"}}},
+		entitiesMissingLabelMetric: {
+			{value: 1, attributes: map[string]any{labelAttribute: "division"}},
+			{value: 0, attributes: map[string]any{labelAttribute: "org"}},
+		},
+	}, gaugeDataPoints(scopeMetrics))
+}
+
+// dataPoint is the value and attributes of a gauge data point
+type dataPoint struct {
+	value      int64
+	attributes map[string]any
+}
+
+// gaugeDataPoints returns the data points of the gauges by metric name
+func gaugeDataPoints(scopeMetrics pmetric.ScopeMetrics) map[string][]dataPoint {
+	result := map[string][]dataPoint{}
+	for i := 0; i < scopeMetrics.Metrics().Len(); i++ {
+		metric := scopeMetrics.Metrics().At(i)
+		dps := metric.Gauge().DataPoints()
+		for j := 0; j < dps.Len(); j++ {
+			result[metric.Name()] = append(result[metric.Name()], dataPoint{value: dps.At(j).IntValue(), attributes: dps.At(j).Attributes().AsRaw()})
+		}
+	}
+	return result
+}
+
+func TestLogsAndMetricsShareTheCatalog(t *testing.T) {
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop"}))
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL
+	set := receivertest.NewNopSettings(factory.Type())
+
+	logsSink, metricsSink := new(consumertest.LogsSink), new(consumertest.MetricsSink)
+	logs, err := factory.CreateLogs(context.Background(), set, cfg, logsSink)
+	require.NoError(t, err)
+	metrics, err := factory.CreateMetrics(context.Background(), set, cfg, metricsSink)
+	require.NoError(t, err)
+	assert.Same(t, logs, metrics, "the signals of a component share the receiver")
+
+	require.NoError(t, logs.Start(context.Background(), componenttest.NewNopHost()))
+	require.NoError(t, metrics.Start(context.Background(), componenttest.NewNopHost()))
+	require.Eventually(t, func() bool {
+		return logsSink.LogRecordCount() > 0 && len(metricsSink.AllMetrics()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, server.Requests(), 1, "the catalog is fetched once")
+
+	assert.NoError(t, logs.Shutdown(context.Background()))
+	assert.NoError(t, metrics.Shutdown(context.Background()))
+
+	other, err := factory.CreateLogs(context.Background(), set, cfg, logsSink)
+	require.NoError(t, err)
+	assert.NotSame(t, logs, other, "a new receiver is created once shut down")
+	assert.NoError(t, other.Shutdown(context.Background()))
+}
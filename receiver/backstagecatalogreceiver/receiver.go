@@ -0,0 +1,151 @@
+package backstagecatalogreceiver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// backoff of the collection while the catalog has no entity, as when the first fetch failed or
+// the extension hasn't loaded the catalog yet
+const (
+	initialRetryInterval = time.Second
+	maxRetryInterval     = time.Minute
+)
+
+// catalogReceiver emits the entities of the catalog at every collection interval. It is shared
+// by the signals of the receiver, each of them converting the entities with its emit function.
+type catalogReceiver struct {
+	logger  *zap.Logger
+	config  Config
+	catalog catalog.Provider // Set on Start when the catalog comes from an extension
+	inline  *catalog.Catalog // Catalog owned by the receiver, nil when using an extension
+
+	// emitters send the entities of a snapshot to the next consumer of every signal. They are
+	// all added before Start.
+	emitters []func(ctx context.Context, entities []catalog.EntityInfo) error
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newCatalogReceiver(set component.TelemetrySettings, cfg *Config) (*catalogReceiver, error) {
+	r := &catalogReceiver{
+		logger:  set.Logger,
+		config:  *cfg,
+		catalog: &catalog.Snapshot{},
+	}
+	if cfg.Extension == nil {
+		inline, err := catalog.New(set, cfg.Config)
+		if err != nil {
+			return nil, err
+		}
+		r.inline = inline
+		r.catalog = inline
+	}
+	return r, nil
+}
+
+// Start fetches the inline catalog, or looks up the backstagecatalog extension, then starts
+// emitting the entities
+func (r *catalogReceiver) Start(ctx context.Context, host component.Host) error {
+	if r.inline != nil {
+		if err := r.inline.Start(ctx); err != nil {
+			return err
+		}
+	} else {
+		ext, found := host.GetExtensions()[*r.config.Extension]
+		if !found {
+			return fmt.Errorf("backstage catalog extension %q not found", r.config.Extension.String())
+		}
+		provider, ok := ext.(catalog.Provider)
+		if !ok {
+			return fmt.Errorf("extension %q is not a backstage catalog", r.config.Extension.String())
+		}
+		r.catalog = provider
+	}
+
+	// the collection outlives the context of Start, it is stopped on Shutdown
+	collectCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go r.collectLoop(collectCtx)
+	return nil
+}
+
+// collectLoop emits the entities once the catalog is loaded, then at every collection interval.
+// Until the catalog has entities, the collection is retried with an exponential backoff capped
+// by the collection interval. The inline catalog without refresh_interval is fetched again
+// before every collection.
+func (r *catalogReceiver) collectLoop(ctx context.Context) {
+	defer r.wg.Done()
+
+	interval := r.config.collectionInterval()
+	retry := min(initialRetryInterval, interval)
+	for !r.collect(ctx) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, maxRetryInterval, interval)
+		r.refresh()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.refresh()
+			r.collect(ctx)
+		}
+	}
+}
+
+// refresh fetches the inline catalog before a collection when it has no background refresh,
+// for the emitted entities not to stay the ones fetched on Start
+func (r *catalogReceiver) refresh() {
+	if r.inline == nil || r.config.RefreshInterval > 0 {
+		return
+	}
+	if err := r.inline.Refresh(); err != nil {
+		r.logger.Error("Failed to refresh the catalog", zap.Error(err))
+	}
+}
+
+// collect emits the entities of the current snapshot. It returns false, emitting nothing,
+// while the catalog has no entity.
+func (r *catalogReceiver) collect(ctx context.Context) bool {
+	entities := r.catalog.Snapshot().Entities()
+	if len(entities) == 0 {
+		r.logger.Debug("No catalog entity to emit")
+		return false
+	}
+	for _, emit := range r.emitters {
+		if err := emit(ctx, entities); err != nil {
+			r.logger.Error("Failed to emit the catalog entities", zap.Error(err))
+		}
+	}
+	return true
+}
+
+// Shutdown stops the collection and the inline catalog refresh loop if running
+func (r *catalogReceiver) Shutdown(ctx context.Context) error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	if r.inline != nil {
+		return r.inline.Shutdown(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package backstagecatalogreceiver
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+
+	"github.com/v1v/opentelemetry-backstage-processor/backstagetest"
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestLogsReceiver(t *testing.T) {
+	checkout := backstagetest.GithubRepository("", "checkout", "acme/checkout", map[string]string{"org": "shop", "tier": "1"})
+	checkout.Spec["owner"] = "group:default/shop-team"
+	checkout.Spec["system"] = "storefront"
+	server := backstagetest.NewServer(t, checkout)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL
+
+	sink := new(consumertest.LogsSink)
+	rcv, err := factory.CreateLogs(context.Background(), receivertest.NewNopSettings(factory.Type()), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, rcv.Shutdown(context.Background())) }()
+
+	require.Eventually(t, func() bool { return sink.LogRecordCount() > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	logs := sink.AllLogs()[0]
+	require.Equal(t, 1, logs.LogRecordCount())
+	scopeLogs := logs.ResourceLogs().At(0).ScopeLogs().At(0)
+	assert.Equal(t, scopeName, scopeLogs.Scope().Name())
+	record := scopeLogs.LogRecords().At(0)
+	assert.Equal(t, "resource:default/checkout", record.Body().Str())
+	assert.Equal(t, map[string]any{
+		kindKey:                 "Resource",
+		namespaceKey:            "default",
+		nameKey:                 "checkout",
+		refKey:                  "resource:default/checkout",
+		typeKey:                 "github-repository",
+		ownerKey:                "group:default/shop-team",
+		systemKey:               "storefront",
+		orgKey:                  "shop",
+		sourceKey:               "default",
+		labelKeyPrefix + "org":  "shop",
+		labelKeyPrefix + "tier": "1",
+	}, record.Attributes().AsRaw())
+}
+
+func TestLogsReceiverEntityEvents(t *testing.T) {
+	extension := component.MustNewID("backstagecatalog")
+	snapshot, err := catalog.NewSnapshot(map[string]catalog.EntityInfo{
+		"component:default/checkout": {Kind: "Component", Namespace: "default", Name: "checkout", EntityRef: "component:default/checkout", Owner: "group:default/shop-team"},
+		"acme-checkout":              {Kind: "Component", Namespace: "default", Name: "checkout", EntityRef: "component:default/checkout", Owner: "group:default/shop-team"},
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Extension = &extension
+	cfg.LogFormat = LogFormatEntityEvent
+	cfg.CollectionInterval = time.Minute
+
+	sink := new(consumertest.LogsSink)
+	rcv, err := factory.CreateLogs(context.Background(), receivertest.NewNopSettings(factory.Type()), cfg, sink)
+	require.NoError(t, err)
+	host := testHost{extensions: map[component.ID]component.Component{
+		extension: testCatalogExtension{Provider: snapshot},
+	}}
+	require.NoError(t, rcv.Start(context.Background(), host))
+	defer func() { assert.NoError(t, rcv.Shutdown(context.Background())) }()
+
+	require.Eventually(t, func() bool { return sink.LogRecordCount() > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	logs := sink.AllLogs()[0]
+	require.Equal(t, 1, logs.LogRecordCount(), "entities are emitted once")
+	scopeLogs := logs.ResourceLogs().At(0).ScopeLogs().At(0)
+	asLog, _ := scopeLogs.Scope().Attributes().Get(entityEventAsLogKey)
+	assert.True(t, asLog.Bool())
+	assert.Equal(t, map[string]any{
+		entityEventTypeKey: entityEventState,
+		entityTypeKey:      entityType,
+		entityIDKey:        map[string]any{refKey: "component:default/checkout"},
+		entityAttributesKey: map[string]any{
+			kindKey:      "Component",
+			namespaceKey: "default",
+			nameKey:      "checkout",
+			ownerKey:     "group:default/shop-team",
+		},
+		entityIntervalKey: int64(60000),
+	}, scopeLogs.LogRecords().At(0).Attributes().AsRaw())
+}
+
+func TestLogsReceiverRetriesEmptyCatalog(t *testing.T) {
+	extension := component.MustNewID("backstagecatalog")
+	provider := &loadingProvider{}
+	provider.snapshot.Store(&catalog.Snapshot{})
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Extension = &extension
+
+	sink := new(consumertest.LogsSink)
+	rcv, err := factory.CreateLogs(context.Background(), receivertest.NewNopSettings(factory.Type()), cfg, sink)
+	require.NoError(t, err)
+	host := testHost{extensions: map[component.ID]component.Component{
+		extension: testCatalogExtension{Provider: provider},
+	}}
+	require.NoError(t, rcv.Start(context.Background(), host))
+	defer func() { assert.NoError(t, rcv.Shutdown(context.Background())) }()
+
+	// the extension loads the catalog after the receiver found it empty
+	require.Eventually(t, func() bool { return provider.reads.Load() > 0 }, 5*time.Second, time.Millisecond)
+	snapshot, err := catalog.NewSnapshot(map[string]catalog.EntityInfo{
+		"component:default/checkout": {Kind: "Component", Name: "checkout", EntityRef: "component:default/checkout"},
+	})
+	require.NoError(t, err)
+	provider.snapshot.Store(snapshot)
+
+	require.Eventually(t, func() bool { return sink.LogRecordCount() > 0 }, 5*time.Second, 10*time.Millisecond,
+		"the entities are emitted once loaded, without waiting for the collection interval")
+}
+
+func TestLogsReceiverRefreshesTheCatalog(t *testing.T) {
+	server := backstagetest.NewServer(t, backstagetest.GithubRepository("", "checkout", "acme/checkout", nil))
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Endpoint = server.URL
+	cfg.CollectionInterval = 50 * time.Millisecond
+
+	sink := new(consumertest.LogsSink)
+	rcv, err := factory.CreateLogs(context.Background(), receivertest.NewNopSettings(factory.Type()), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(context.Background(), componenttest.NewNopHost()))
+	defer func() { assert.NoError(t, rcv.Shutdown(context.Background())) }()
+
+	require.Eventually(t, func() bool { return sink.LogRecordCount() > 0 }, 5*time.Second, 10*time.Millisecond)
+	server.SetEntities(backstagetest.GithubRepository("", "billing", "acme/billing", nil))
+
+	// without refresh_interval, the catalog is fetched again before every collection
+	require.Eventually(t, func() bool {
+		logs := sink.AllLogs()
+		last := logs[len(logs)-1].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+		return last.Len() == 1 && last.At(0).Body().Str() == "resource:default/billing"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReceiverMissingExtension(t *testing.T) {
+	extension := component.MustNewID("backstagecatalog")
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Extension = &extension
+
+	rcv, err := factory.CreateLogs(context.Background(), receivertest.NewNopSettings(factory.Type()), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.EqualError(t, rcv.Start(context.Background(), componenttest.NewNopHost()), `backstage catalog extension "backstagecatalog" not found`)
+	assert.NoError(t, rcv.Shutdown(context.Background()))
+}
+
+// testCatalogExtension is a backstagecatalog extension serving a fixed snapshot
+type testCatalogExtension struct {
+	component.StartFunc
+	component.ShutdownFunc
+	catalog.Provider
+}
+
+// loadingProvider is a catalog whose snapshot changes, as an extension loading the catalog
+type loadingProvider struct {
+	snapshot atomic.Pointer[catalog.Snapshot]
+	reads    atomic.Int32
+}
+
+func (p *loadingProvider) Lookup(key string) (catalog.EntityInfo, bool) {
+	return p.Snapshot().Lookup(key)
+}
+
+func (p *loadingProvider) Snapshot() *catalog.Snapshot {
+	p.reads.Add(1)
+	return p.snapshot.Load()
+}
+
+// testHost is a host holding the given extensions
+type testHost struct {
+	component.Host
+	extensions map[component.ID]component.Component
+}
+
+func (h testHost) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
@@ -0,0 +1,67 @@
+package backstagecatalogreceiver
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// sharedReceivers holds the receivers shared by the signals of a component, as the
+// sharedcomponent package of the collector does for the multi-signal receivers
+type sharedReceivers struct {
+	mu        sync.Mutex
+	receivers map[component.ID]*sharedReceiver
+}
+
+// sharedReceiver is a catalog receiver started and shut down once, whatever the number of
+// signals it is created for
+type sharedReceiver struct {
+	*catalogReceiver
+
+	startOnce    sync.Once
+	startErr     error
+	shutdownOnce sync.Once
+	shutdownErr  error
+	remove       func()
+}
+
+// getOrCreate returns the receiver of the component, creating it for the first signal
+func (s *sharedReceivers) getOrCreate(id component.ID, create func() (*catalogReceiver, error)) (*sharedReceiver, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.receivers[id]; ok {
+		return r, nil
+	}
+	r, err := create()
+	if err != nil {
+		return nil, err
+	}
+	shared := &sharedReceiver{catalogReceiver: r}
+	shared.remove = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.receivers, id)
+	}
+	s.receivers[id] = shared
+	return shared, nil
+}
+
+// Start starts the receiver for the first signal
+func (r *sharedReceiver) Start(ctx context.Context, host component.Host) error {
+	r.startOnce.Do(func() {
+		r.startErr = r.catalogReceiver.Start(ctx, host)
+	})
+	return r.startErr
+}
+
+// Shutdown shuts the receiver down for the first signal, and forgets it so that a new
+// receiver is created when the collector reloads its configuration
+func (r *sharedReceiver) Shutdown(ctx context.Context) error {
+	r.shutdownOnce.Do(func() {
+		r.shutdownErr = r.catalogReceiver.Shutdown(ctx)
+		r.remove()
+	})
+	return r.shutdownErr
+}
@@ -0,0 +1,120 @@
+package backstageprocessor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// defaults of the sampling hints
+const (
+	defaultTierLabel         = "tier"
+	defaultPriorityAttribute = "sampling.priority"
+)
+
+// maxThreshold is the number of distinct sampling thresholds, 2^56, of the OpenTelemetry
+// consistent probability sampling.
+const maxThreshold = 1 << 56
+
+// SamplingConfig sets sampling hints on spans according to the tier label of their entity,
+// so that a downstream sampler can keep the traces of the most critical services.
+type SamplingConfig struct {
+	// Label is the entity label holding the tier. Defaults to tier.
+	Label string `mapstructure:"label"`
+
+	// Attribute is the span attribute set to the priority of the tier. Defaults to sampling.priority.
+	Attribute string `mapstructure:"attribute"`
+
+	// Tiers maps the values of the tier label to their sampling hints.
+	Tiers map[string]TierSamplingConfig `mapstructure:"tiers"`
+}
+
+// TierSamplingConfig defines the sampling hints of a tier.
+type TierSamplingConfig struct {
+	// Priority is the value of the priority attribute. The attribute isn't set when empty.
+	Priority *int64 `mapstructure:"priority"`
+
+	// Probability sets the sampling threshold of the span, the th sub-key of the ot entry of
+	// its W3C tracestate, to keep the span with that probability. The tracestate isn't
+	// changed when empty.
+	Probability *float64 `mapstructure:"probability"`
+}
+
+// Validate checks if the sampling configuration is valid.
+func (cfg *SamplingConfig) Validate() error {
+	for tier, hints := range cfg.Tiers {
+		if p := hints.Probability; p != nil && (*p < 1.0/maxThreshold || *p > 1) {
+			return fmt.Errorf("tier %q: probability must be within (0, 1]", tier)
+		}
+	}
+	return nil
+}
+
+// label returns the configured tier label, falling back to tier.
+func (cfg *SamplingConfig) label() string {
+	if cfg.Label == "" {
+		return defaultTierLabel
+	}
+	return cfg.Label
+}
+
+// attribute returns the configured priority attribute, falling back to sampling.priority.
+func (cfg *SamplingConfig) attribute() string {
+	if cfg.Attribute == "" {
+		return defaultPriorityAttribute
+	}
+	return cfg.Attribute
+}
+
+// apply sets the sampling hints of the tier of the entity on the span
+func (cfg *SamplingConfig) apply(span ptrace.Span, info catalog.EntityInfo) {
+	hints, ok := cfg.Tiers[info.Labels[cfg.label()]]
+	if !ok {
+		return
+	}
+	if hints.Priority != nil {
+		span.Attributes().PutInt(cfg.attribute(), *hints.Priority)
+	}
+	if hints.Probability != nil {
+		span.TraceState().FromRaw(withThreshold(span.TraceState().AsRaw(), threshold(*hints.Probability)))
+	}
+}
+
+// threshold returns the rejection threshold of a sampling probability, encoded as the
+// th value of the OpenTelemetry tracestate: up to 14 hexadecimal digits, without trailing zeros.
+func threshold(probability float64) string {
+	t := maxThreshold - uint64(math.Round(probability*maxThreshold))
+	if t == 0 {
+		return "0"
+	}
+	return strings.TrimRight(fmt.Sprintf("%014x", t), "0")
+}
+
+// withThreshold sets the th sub-key of the ot entry of a W3C tracestate, keeping its other
+// sub-keys and the entries of other vendors. The modified ot entry is moved first, as
+// required by the W3C specification.
+func withThreshold(tracestate string, th string) string {
+	ot := []string{"th:" + th}
+	var others []string
+	for _, member := range strings.Split(tracestate, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		value, isOT := strings.CutPrefix(member, "ot=")
+		if !isOT {
+			others = append(others, member)
+			continue
+		}
+		for _, field := range strings.Split(value, ";") {
+			if field != "" && !strings.HasPrefix(field, "th:") {
+				ot = append(ot, field)
+			}
+		}
+	}
+	return strings.Join(append([]string{"ot=" + strings.Join(ot, ";")}, others...), ",")
+}
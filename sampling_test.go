@@ -0,0 +1,100 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestThreshold(t *testing.T) {
+	tests := []struct {
+		probability float64
+		expected    string
+	}{
+		{probability: 1, expected: "0"},
+		{probability: 0.5, expected: "8"},
+		{probability: 0.25, expected: "c"},
+		{probability: 0.1, expected: "e6666666666666"},
+		{probability: 1.0 / maxThreshold, expected: "ffffffffffffff"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, threshold(tt.probability), tt.probability)
+	}
+}
+
+func TestWithThreshold(t *testing.T) {
+	tests := []struct {
+		tracestate string
+		expected   string
+	}{
+		{tracestate: "", expected: "ot=th:8"},
+		{tracestate: "vendor=value", expected: "ot=th:8,vendor=value"},
+		{tracestate: "vendor=value,ot=th:c;rv:abcdef12345678", expected: "ot=th:8;rv:abcdef12345678,vendor=value"},
+		{tracestate: "ot=rv:abcdef12345678", expected: "ot=th:8;rv:abcdef12345678"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, withThreshold(tt.tracestate, "8"), tt.tracestate)
+	}
+}
+
+func TestSamplingConfigValidate(t *testing.T) {
+	valid, zero := 0.5, 0.0
+	assert.NoError(t, (&SamplingConfig{Tiers: map[string]TierSamplingConfig{"1": {Probability: &valid}}}).Validate())
+	assert.EqualError(t, (&SamplingConfig{Tiers: map[string]TierSamplingConfig{"4": {Probability: &zero}}}).Validate(),
+		`tier "4": probability must be within (0, 1]`)
+}
+
+func TestSamplingHints(t *testing.T) {
+	critical, low := int64(100), int64(1)
+	half := 0.5
+	processor := &backstageprocessor{
+		logger: zap.NewNop(),
+		config: Config{Sampling: SamplingConfig{Tiers: map[string]TierSamplingConfig{
+			"1": {Priority: &critical},
+			"4": {Priority: &low, Probability: &half},
+		}}},
+		catalog: &catalog.Snapshot{Keys: map[string]catalog.EntityInfo{
+			"checkout": {Org: "shop", Labels: map[string]string{"tier": "1"}},
+			"search":   {Org: "discovery", Labels: map[string]string{"tier": "4"}},
+			"cart":     {Org: "shop"},
+		}},
+	}
+
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr(serviceNameKey, "checkout")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	spans.AppendEmpty().SetName("resource entity")
+	search := spans.AppendEmpty()
+	search.SetName("span entity")
+	search.Attributes().PutStr(serviceNameKey, "search")
+	search.TraceState().FromRaw("vendor=value")
+	cart := spans.AppendEmpty()
+	cart.SetName("entity without tier")
+	cart.Attributes().PutStr(serviceNameKey, "cart")
+
+	td, err := processor.processTraces(context.Background(), td)
+	require.NoError(t, err)
+
+	priority, ok := spans.At(0).Attributes().Get(defaultPriorityAttribute)
+	require.True(t, ok, "spans use the entity of their resource")
+	assert.Equal(t, int64(100), priority.Int())
+	assert.Empty(t, spans.At(0).TraceState().AsRaw())
+
+	priority, ok = spans.At(1).Attributes().Get(defaultPriorityAttribute)
+	require.True(t, ok, "spans use their own entity first")
+	assert.Equal(t, int64(1), priority.Int())
+	assert.Equal(t, "ot=th:8,vendor=value", spans.At(1).TraceState().AsRaw())
+
+	_, ok = spans.At(2).Attributes().Get(defaultPriorityAttribute)
+	assert.False(t, ok, "entities without tier get no hints")
+
+	_, ok = td.ResourceSpans().At(0).Resource().Attributes().Get(defaultPriorityAttribute)
+	assert.False(t, ok, "hints are only set on spans")
+}
@@ -0,0 +1,49 @@
+package backstageprocessor
+
+import (
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const scopeName = "github.com/v1v/opentelemetry-backstage-processor"
+
+// signals reported in the signal attribute of the processor metrics
+const (
+	signalTraces   = "traces"
+	signalLogs     = "logs"
+	signalMetrics  = "metrics"
+	signalProfiles = "profiles"
+)
+
+// processorTelemetry holds the internal metrics reported by the processor
+type processorTelemetry struct {
+	droppedItems        metric.Int64Counter
+	cardinalityOverflow metric.Int64Counter
+}
+
+func newProcessorTelemetry(set component.TelemetrySettings) (*processorTelemetry, error) {
+	meter := set.MeterProvider.Meter(scopeName)
+
+	droppedItems, err := meter.Int64Counter(
+		"otelcol_backstage_processor_dropped_items",
+		metric.WithDescription("Number of spans, log records, metric data points and profile samples dropped by the filter policy"),
+		metric.WithUnit("{items}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cardinalityOverflow, err := meter.Int64Counter(
+		"otelcol_backstage_processor_cardinality_overflow",
+		metric.WithDescription("Number of data point attribute values collapsed by the cardinality limit"),
+		metric.WithUnit("{values}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &processorTelemetry{
+		droppedItems:        droppedItems,
+		cardinalityOverflow: cardinalityOverflow,
+	}, nil
+}
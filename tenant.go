@@ -0,0 +1,260 @@
+package backstageprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/xconsumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pprofile"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+// defaultTenantAttribute is the resource attribute set to the tenant by default.
+const defaultTenantAttribute = "backstage.tenant"
+
+// entity fields the tenant can be read from, labels are read with the labels. prefix
+const (
+	tenantFieldOrg       = "org"
+	tenantFieldDivision  = "division"
+	tenantFieldNamespace = "namespace"
+	tenantFieldName      = "name"
+	tenantLabelPrefix    = "labels."
+)
+
+// TenantConfig resolves the tenant of the telemetry from its entity, for the backends
+// isolating the data of each team, such as the X-Scope-OrgID header of Loki, Mimir and Tempo.
+type TenantConfig struct {
+	// Field is the entity field holding the tenant: org, division, namespace, name or
+	// labels.<key>. The tenant isn't resolved when empty.
+	Field string `mapstructure:"field"`
+
+	// Attribute is the resource attribute set to the tenant. Defaults to backstage.tenant.
+	Attribute string `mapstructure:"attribute"`
+
+	// MetadataKey is the client metadata key set to the tenant, to be read by the
+	// headers_setter extension. Batches holding several tenants are split by tenant.
+	// The client metadata isn't set when empty.
+	MetadataKey string `mapstructure:"metadata_key"`
+
+	// Default is the tenant of the telemetry matching no entity, or whose entity has an
+	// empty field. The telemetry has no tenant when empty.
+	Default string `mapstructure:"default"`
+}
+
+// Validate checks if the tenant configuration is valid.
+func (cfg *TenantConfig) Validate() error {
+	switch {
+	case cfg.Field == "":
+		if cfg.MetadataKey != "" || cfg.Default != "" {
+			return errors.New("tenant.field must be set to resolve the tenant")
+		}
+	case cfg.Field == tenantFieldOrg, cfg.Field == tenantFieldDivision,
+		cfg.Field == tenantFieldNamespace, cfg.Field == tenantFieldName:
+	case strings.HasPrefix(cfg.Field, tenantLabelPrefix) && len(cfg.Field) > len(tenantLabelPrefix):
+	default:
+		return fmt.Errorf("unknown tenant field %q", cfg.Field)
+	}
+	return nil
+}
+
+// enabled reports whether the tenant is resolved.
+func (cfg *TenantConfig) enabled() bool {
+	return cfg.Field != ""
+}
+
+// attribute returns the configured tenant attribute, falling back to backstage.tenant.
+func (cfg *TenantConfig) attribute() string {
+	if cfg.Attribute == "" {
+		return defaultTenantAttribute
+	}
+	return cfg.Attribute
+}
+
+// tenant returns the tenant of an entity, falling back to the default tenant.
+func (cfg *TenantConfig) tenant(info catalog.EntityInfo, matched bool) string {
+	if !matched {
+		return cfg.Default
+	}
+
+	var tenant string
+	switch cfg.Field {
+	case tenantFieldOrg:
+		tenant = info.Org
+	case tenantFieldDivision:
+		tenant = info.Division
+	case tenantFieldNamespace:
+		tenant = info.Namespace
+	case tenantFieldName:
+		tenant = info.Name
+	default:
+		tenant = info.Labels[strings.TrimPrefix(cfg.Field, tenantLabelPrefix)]
+	}
+	if tenant == "" {
+		return cfg.Default
+	}
+	return tenant
+}
+
+// apply sets the tenant attribute of the resource attributes, if any. A tenant attribute set
+// by the sender is always removed, the attribute only holds the tenant resolved by the processor.
+func (cfg *TenantConfig) apply(attributes pcommon.Map, info catalog.EntityInfo, matched bool) {
+	attributes.Remove(cfg.attribute())
+	if tenant := cfg.tenant(info, matched); tenant != "" {
+		attributes.PutStr(cfg.attribute(), tenant)
+	}
+}
+
+// withTenant returns the context with the client metadata key set to the tenant. A value of
+// the key sent by the client is always removed, even for telemetry without tenant, the
+// metadata key only holds the tenant resolved by the processor.
+func (cfg *TenantConfig) withTenant(ctx context.Context, tenant string) context.Context {
+	info := client.FromContext(ctx)
+	metadata := map[string][]string{}
+	for key := range info.Metadata.Keys() {
+		if !strings.EqualFold(key, cfg.MetadataKey) {
+			metadata[key] = info.Metadata.Get(key)
+		}
+	}
+	if tenant != "" {
+		metadata[cfg.MetadataKey] = []string{tenant}
+	}
+	info.Metadata = client.NewMetadata(metadata)
+	return client.NewContext(ctx, info)
+}
+
+// resourceTenant returns the tenant of a processed resource. The processor resolves the tenant
+// once, when it rewrites the tenant attribute: the attribute set by the sender is removed, it only
+// holds the tenant resolved from the entity.
+func (cfg *TenantConfig) resourceTenant(resource pcommon.Resource) string {
+	tenant, ok := resource.Attributes().Get(cfg.attribute())
+	if !ok {
+		return ""
+	}
+	return tenant.Str()
+}
+
+// tenantTraces sets the client metadata of the enriched traces, splitting the batches
+// holding several tenants. It is placed after the processor, which resolves the tenants.
+type tenantTraces struct {
+	consumer.Traces
+	config TenantConfig
+}
+
+func (t tenantTraces) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	batches := map[string]ptrace.Traces{}
+	var tenants []string
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		tenant := t.config.resourceTenant(rs.Resource())
+		batch, ok := batches[tenant]
+		if !ok {
+			batch = ptrace.NewTraces()
+			batches[tenant] = batch
+			tenants = append(tenants, tenant)
+		}
+		rs.MoveTo(batch.ResourceSpans().AppendEmpty())
+		return true
+	})
+
+	var errs []error
+	for _, tenant := range tenants {
+		errs = append(errs, t.Traces.ConsumeTraces(t.config.withTenant(ctx, tenant), batches[tenant]))
+	}
+	return errors.Join(errs...)
+}
+
+// tenantLogs sets the client metadata of the enriched logs, splitting the batches
+// holding several tenants.
+type tenantLogs struct {
+	consumer.Logs
+	config TenantConfig
+}
+
+func (t tenantLogs) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	batches := map[string]plog.Logs{}
+	var tenants []string
+	ld.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		tenant := t.config.resourceTenant(rl.Resource())
+		batch, ok := batches[tenant]
+		if !ok {
+			batch = plog.NewLogs()
+			batches[tenant] = batch
+			tenants = append(tenants, tenant)
+		}
+		rl.MoveTo(batch.ResourceLogs().AppendEmpty())
+		return true
+	})
+
+	var errs []error
+	for _, tenant := range tenants {
+		errs = append(errs, t.Logs.ConsumeLogs(t.config.withTenant(ctx, tenant), batches[tenant]))
+	}
+	return errors.Join(errs...)
+}
+
+// tenantMetrics sets the client metadata of the enriched metrics, splitting the batches
+// holding several tenants.
+type tenantMetrics struct {
+	consumer.Metrics
+	config TenantConfig
+}
+
+func (t tenantMetrics) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	batches := map[string]pmetric.Metrics{}
+	var tenants []string
+	md.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		tenant := t.config.resourceTenant(rm.Resource())
+		batch, ok := batches[tenant]
+		if !ok {
+			batch = pmetric.NewMetrics()
+			batches[tenant] = batch
+			tenants = append(tenants, tenant)
+		}
+		rm.MoveTo(batch.ResourceMetrics().AppendEmpty())
+		return true
+	})
+
+	var errs []error
+	for _, tenant := range tenants {
+		errs = append(errs, t.Metrics.ConsumeMetrics(t.config.withTenant(ctx, tenant), batches[tenant]))
+	}
+	return errors.Join(errs...)
+}
+
+// tenantProfiles sets the client metadata of the enriched profiles, splitting the batches
+// holding several tenants. The dictionary is copied to every batch.
+type tenantProfiles struct {
+	xconsumer.Profiles
+	config TenantConfig
+}
+
+func (t tenantProfiles) ConsumeProfiles(ctx context.Context, pd pprofile.Profiles) error {
+	batches := map[string]pprofile.Profiles{}
+	var tenants []string
+	pd.ResourceProfiles().RemoveIf(func(rp pprofile.ResourceProfiles) bool {
+		tenant := t.config.resourceTenant(rp.Resource())
+		batch, ok := batches[tenant]
+		if !ok {
+			batch = pprofile.NewProfiles()
+			pd.Dictionary().CopyTo(batch.Dictionary())
+			batches[tenant] = batch
+			tenants = append(tenants, tenant)
+		}
+		rp.MoveTo(batch.ResourceProfiles().AppendEmpty())
+		return true
+	})
+
+	var errs []error
+	for _, tenant := range tenants {
+		errs = append(errs, t.Profiles.ConsumeProfiles(t.config.withTenant(ctx, tenant), batches[tenant]))
+	}
+	return errors.Join(errs...)
+}
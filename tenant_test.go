@@ -0,0 +1,197 @@
+package backstageprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+
+	"github.com/v1v/opentelemetry-backstage-processor/catalog"
+)
+
+func TestTenantConfig(t *testing.T) {
+	info := catalog.EntityInfo{Org: "shop", Division: "retail", Namespace: "team-a", Name: "checkout", Labels: map[string]string{"tenant": "acme"}}
+
+	tests := []struct {
+		name     string
+		config   TenantConfig
+		info     catalog.EntityInfo
+		matched  bool
+		expected string
+	}{
+		{name: "org", config: TenantConfig{Field: "org"}, info: info, matched: true, expected: "shop"},
+		{name: "division", config: TenantConfig{Field: "division"}, info: info, matched: true, expected: "retail"},
+		{name: "namespace", config: TenantConfig{Field: "namespace"}, info: info, matched: true, expected: "team-a"},
+		{name: "name", config: TenantConfig{Field: "name"}, info: info, matched: true, expected: "checkout"},
+		{name: "label", config: TenantConfig{Field: "labels.tenant"}, info: info, matched: true, expected: "acme"},
+		{name: "missing label", config: TenantConfig{Field: "labels.team", Default: "anonymous"}, info: info, matched: true, expected: "anonymous"},
+		{name: "unmatched", config: TenantConfig{Field: "org", Default: "anonymous"}, expected: "anonymous"},
+		{name: "unmatched without default", config: TenantConfig{Field: "org"}, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.config.Validate())
+			assert.Equal(t, tt.expected, tt.config.tenant(tt.info, tt.matched))
+		})
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, (&TenantConfig{}).Validate())
+		assert.EqualError(t, (&TenantConfig{Field: "team"}).Validate(), `unknown tenant field "team"`)
+		assert.EqualError(t, (&TenantConfig{Field: "labels."}).Validate(), `unknown tenant field "labels."`)
+		assert.EqualError(t, (&TenantConfig{MetadataKey: "X-Scope-OrgID"}).Validate(), "tenant.field must be set to resolve the tenant")
+	})
+}
+
+// newTenantHost returns a host with a backstagecatalog extension serving a fixed catalog
+func newTenantHost(t *testing.T) (testHost, *component.ID) {
+	t.Helper()
+	extensionID := component.MustNewID("backstagecatalog")
+	snapshot, err := catalog.NewSnapshot(map[string]catalog.EntityInfo{
+		"checkout": {Org: "shop", EntityRef: "component:default/checkout"},
+		"billing":  {Org: "payments", EntityRef: "component:default/billing"},
+	})
+	require.NoError(t, err)
+	return testHost{extensions: map[component.ID]component.Component{
+		extensionID: testCatalogExtension{Provider: snapshot},
+	}}, &extensionID
+}
+
+func TestTenantMetadata(t *testing.T) {
+	host, extensionID := newTenantHost(t)
+	cfg := &Config{
+		Extension: extensionID,
+		Tenant:    TenantConfig{Field: "org", MetadataKey: "X-Scope-OrgID", Default: "anonymous"},
+	}
+
+	sink := new(consumertest.TracesSink)
+	factory := NewFactory()
+	processor, err := factory.CreateTraces(context.Background(), processortest.NewNopSettings(factory.Type()), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, processor.Start(context.Background(), host))
+	defer func() { assert.NoError(t, processor.Shutdown(context.Background())) }()
+
+	traces := ptrace.NewTraces()
+	for _, service := range []string{"checkout", "billing", "unknown", "checkout"} {
+		rs := traces.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr(serviceNameKey, service)
+		// the tenant set by the sender is ignored
+		rs.Resource().Attributes().PutStr(defaultTenantAttribute, "payments")
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName(service)
+	}
+	ctx := client.NewContext(context.Background(), client.Info{Metadata: client.NewMetadata(map[string][]string{"x-forwarded-for": {"10.0.0.1"}})})
+	require.NoError(t, processor.ConsumeTraces(ctx, traces))
+
+	require.Len(t, sink.AllTraces(), 3, "one batch per tenant")
+	expected := []struct {
+		tenant string
+		spans  int
+	}{{"shop", 2}, {"payments", 1}, {"anonymous", 1}}
+	for i, e := range expected {
+		metadata := client.FromContext(sink.Contexts()[i]).Metadata
+		assert.Equal(t, []string{e.tenant}, metadata.Get("X-Scope-OrgID"))
+		assert.Equal(t, []string{"10.0.0.1"}, metadata.Get("x-forwarded-for"), "keeps the incoming metadata")
+
+		batch := sink.AllTraces()[i]
+		assert.Equal(t, e.spans, batch.SpanCount())
+		tenant, _ := batch.ResourceSpans().At(0).Resource().Attributes().Get(defaultTenantAttribute)
+		assert.Equal(t, e.tenant, tenant.Str())
+	}
+}
+
+func TestTenantAttribute(t *testing.T) {
+	host, extensionID := newTenantHost(t)
+	cfg := &Config{
+		Extension: extensionID,
+		Tenant:    TenantConfig{Field: "org", Attribute: "tenant"},
+	}
+
+	sink := new(consumertest.LogsSink)
+	factory := NewFactory()
+	processor, err := factory.CreateLogs(context.Background(), processortest.NewNopSettings(factory.Type()), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, processor.Start(context.Background(), host))
+	defer func() { assert.NoError(t, processor.Shutdown(context.Background())) }()
+
+	logs := plog.NewLogs()
+	for _, service := range []string{"checkout", "unknown"} {
+		rl := logs.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().PutStr(serviceNameKey, service)
+		rl.Resource().Attributes().PutStr("tenant", "payments")
+		rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	}
+	require.NoError(t, processor.ConsumeLogs(context.Background(), logs))
+
+	require.Len(t, sink.AllLogs(), 1, "batches are only split for the client metadata")
+	resources := sink.AllLogs()[0].ResourceLogs()
+	tenant, ok := resources.At(0).Resource().Attributes().Get("tenant")
+	require.True(t, ok)
+	assert.Equal(t, "shop", tenant.Str())
+	_, ok = resources.At(1).Resource().Attributes().Get("tenant")
+	assert.False(t, ok, "unmatched telemetry has no tenant without default, even if the sender set one")
+}
+
+func TestTenantMetadataSpoofed(t *testing.T) {
+	host, extensionID := newTenantHost(t)
+	cfg := &Config{
+		Extension: extensionID,
+		Tenant:    TenantConfig{Field: "org", MetadataKey: "X-Scope-OrgID"},
+	}
+
+	sink := new(consumertest.LogsSink)
+	factory := NewFactory()
+	processor, err := factory.CreateLogs(context.Background(), processortest.NewNopSettings(factory.Type()), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, processor.Start(context.Background(), host))
+	defer func() { assert.NoError(t, processor.Shutdown(context.Background())) }()
+
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr(serviceNameKey, "unknown")
+	rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	ctx := client.NewContext(context.Background(), client.Info{Metadata: client.NewMetadata(map[string][]string{
+		"x-scope-orgid":   {"payments"},
+		"x-forwarded-for": {"10.0.0.1"},
+	})})
+	require.NoError(t, processor.ConsumeLogs(ctx, logs))
+
+	require.Len(t, sink.AllLogs(), 1)
+	metadata := client.FromContext(sink.Contexts()[0]).Metadata
+	assert.Empty(t, metadata.Get("X-Scope-OrgID"), "unmatched telemetry has no tenant, even if the client sent one")
+	assert.Equal(t, []string{"10.0.0.1"}, metadata.Get("x-forwarded-for"), "keeps the incoming metadata")
+}
+
+func TestTenantLogsSplitByResolvedTenant(t *testing.T) {
+	sink := new(consumertest.LogsSink)
+	next := tenantLogs{Logs: sink, config: TenantConfig{Field: "org", Attribute: "tenant", MetadataKey: "X-Scope-OrgID"}}
+
+	logs := plog.NewLogs()
+	// the service names aren't matched again, the tenant attribute holds the tenant resolved
+	// by the processor
+	for _, resource := range []struct{ service, tenant string }{{"checkout", "payments"}, {"billing", ""}, {"checkout", "shop"}} {
+		rl := logs.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().PutStr(serviceNameKey, resource.service)
+		if resource.tenant != "" {
+			rl.Resource().Attributes().PutStr("tenant", resource.tenant)
+		}
+		rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	}
+	require.NoError(t, next.ConsumeLogs(context.Background(), logs))
+
+	require.Len(t, sink.AllLogs(), 3)
+	for i, tenant := range []string{"payments", "", "shop"} {
+		metadata := client.FromContext(sink.Contexts()[i]).Metadata
+		if tenant == "" {
+			assert.Empty(t, metadata.Get("X-Scope-OrgID"))
+			continue
+		}
+		assert.Equal(t, []string{tenant}, metadata.Get("X-Scope-OrgID"))
+	}
+}